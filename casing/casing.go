@@ -0,0 +1,140 @@
+// Package casing 在proto标识符与各种命名风格之间转换.
+//
+// 除JSONName外,所有转换都先用Words把名称拆成单词再重新拼接,规则如下:
+//
+//   - _ - . 以及空白是分隔符,连续的分隔符以及开头和结尾的分隔符会被忽略
+//   - 小写字母或数字之后的大写字母开始一个新单词: fooBar -> foo Bar
+//   - 连续的大写字母是一个缩写词,缩写词之后如果是小写字母,
+//     缩写词的最后一个字母属于下一个单词: HTTPServer -> HTTP Server
+//   - 数字属于它前面的单词: int32_value -> int32 value, HTTP2Server -> HTTP2 Server
+//
+// LowerCamel与Title对缩写词的处理相同: 单词只把首字母转为大写,其余字母保持不变,
+// 所以缩写词保持大写,与Go的命名习惯一致; LowerCamel只额外把第一个单词整体转为小写.
+//
+// 各风格的转换结果:
+//
+//	名称            LowerCamel     Title          Snake            ScreamingSnake    Kebab
+//	foo_bar         fooBar         FooBar         foo_bar          FOO_BAR           foo-bar
+//	HTTPServer      httpServer     HTTPServer     http_server      HTTP_SERVER       http-server
+//	HTTPServerID    httpServerID   HTTPServerID   http_server_id   HTTP_SERVER_ID    http-server-id
+//	get_url2_path   getUrl2Path    GetUrl2Path    get_url2_path    GET_URL2_PATH     get-url2-path
+package casing
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Words 把名称拆分为单词,拆分规则见包文档
+func Words(s string) []string {
+	var words []string
+	rs := []rune(s)
+	start := -1
+	flush := func(end int) {
+		if start >= 0 && end > start {
+			words = append(words, string(rs[start:end]))
+		}
+		start = -1
+	}
+	for i, r := range rs {
+		if _IsSeparator(r) {
+			flush(i)
+			continue
+		}
+		if start < 0 {
+			start = i
+			continue
+		}
+		if unicode.IsUpper(r) {
+			prev := rs[i-1]
+			nextLower := i+1 < len(rs) && unicode.IsLower(rs[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush(i)
+				start = i
+			}
+		}
+	}
+	flush(len(rs))
+	return words
+}
+
+// LowerCamel 转换为首字母小写的驼峰命名,第一个单词整体小写,
+// 其余单词与Title相同: HTTPServerID -> httpServerID
+func LowerCamel(s string) string {
+	words := Words(s)
+	for i, w := range words {
+		if i == 0 {
+			words[i] = strings.ToLower(w)
+		} else {
+			words[i] = _Capitalize(w)
+		}
+	}
+	return strings.Join(words, "")
+}
+
+// Title 转换为首字母大写的驼峰命名,每个单词首字母大写,其余字母保持不变: HTTPServerID -> HTTPServerID
+func Title(s string) string {
+	words := Words(s)
+	for i, w := range words {
+		words[i] = _Capitalize(w)
+	}
+	return strings.Join(words, "")
+}
+
+// Snake 转换为小写下划线命名: FooBar -> foo_bar
+func Snake(s string) string {
+	return _JoinWords(s, "_", strings.ToLower)
+}
+
+// ScreamingSnake 转换为大写下划线命名,通常用于枚举值: FooBar -> FOO_BAR
+func ScreamingSnake(s string) string {
+	return _JoinWords(s, "_", strings.ToUpper)
+}
+
+// Kebab 转换为小写中划线命名: FooBar -> foo-bar
+func Kebab(s string) string {
+	return _JoinWords(s, "-", strings.ToLower)
+}
+
+// JSONName 与protoc计算字段json_name的规则一致:
+// 去掉下划线并把下划线之后的字母转为大写,其他字符保持不变. foo_bar_2 -> fooBar2
+func JSONName(s string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range s {
+		switch {
+		case r == '_':
+			upper = true
+		case upper:
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// _JoinWords 把s拆分后的单词用fn转换,再用sep连接
+func _JoinWords(s, sep string, fn func(string) string) string {
+	words := Words(s)
+	for i, w := range words {
+		words[i] = fn(w)
+	}
+	return strings.Join(words, sep)
+}
+
+// _Capitalize 把单词的第一个字母转为大写
+func _Capitalize(w string) string {
+	rs := []rune(w)
+	if len(rs) == 0 {
+		return w
+	}
+	rs[0] = unicode.ToUpper(rs[0])
+	return string(rs)
+}
+
+// _IsSeparator 判断r是否为单词分隔符
+func _IsSeparator(r rune) bool {
+	return r == '_' || r == '-' || r == '.' || unicode.IsSpace(r)
+}
@@ -0,0 +1,118 @@
+package casing
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want []string
+	}{
+		{in: "", want: nil},
+		{in: "foo", want: []string{"foo"}},
+		{in: "foo_bar", want: []string{"foo", "bar"}},
+		{in: "foo__bar", want: []string{"foo", "bar"}},
+		{in: "foo_bar_", want: []string{"foo", "bar"}},
+		{in: "_foo", want: []string{"foo"}},
+		{in: "___", want: nil},
+		{in: "fooBar", want: []string{"foo", "Bar"}},
+		{in: "FooBar", want: []string{"Foo", "Bar"}},
+		{in: "HTTPServer", want: []string{"HTTP", "Server"}},
+		{in: "getHTTP", want: []string{"get", "HTTP"}},
+		{in: "ID", want: []string{"ID"}},
+		{in: "HTTP2Server", want: []string{"HTTP2", "Server"}},
+		{in: "int32_value", want: []string{"int32", "value"}},
+		{in: "get_url2_path", want: []string{"get", "url2", "path"}},
+		{in: "v2alpha", want: []string{"v2alpha"}},
+		{in: "foo2Bar", want: []string{"foo2", "Bar"}},
+		{in: "2fa_code", want: []string{"2fa", "code"}},
+		{in: "my-kebab.name here", want: []string{"my", "kebab", "name", "here"}},
+	} {
+		if got := Words(tc.in); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Words(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestConversions(t *testing.T) {
+	for _, tc := range []struct {
+		in             string
+		lowerCamel     string
+		title          string
+		snake          string
+		screamingSnake string
+		kebab          string
+	}{
+		// 包文档中的表格
+		{"foo_bar", "fooBar", "FooBar", "foo_bar", "FOO_BAR", "foo-bar"},
+		{"HTTPServer", "httpServer", "HTTPServer", "http_server", "HTTP_SERVER", "http-server"},
+		{"HTTPServerID", "httpServerID", "HTTPServerID", "http_server_id", "HTTP_SERVER_ID", "http-server-id"},
+		{"get_url2_path", "getUrl2Path", "GetUrl2Path", "get_url2_path", "GET_URL2_PATH", "get-url2-path"},
+		// 空的分隔段
+		{"foo__bar", "fooBar", "FooBar", "foo_bar", "FOO_BAR", "foo-bar"},
+		{"foo_bar_", "fooBar", "FooBar", "foo_bar", "FOO_BAR", "foo-bar"},
+		{"_foo", "foo", "Foo", "foo", "FOO", "foo"},
+		{"", "", "", "", "", ""},
+		// 缩写词与数字
+		{"HTTP2Server", "http2Server", "HTTP2Server", "http2_server", "HTTP2_SERVER", "http2-server"},
+		{"int32_value", "int32Value", "Int32Value", "int32_value", "INT32_VALUE", "int32-value"},
+		// 缩写词在LowerCamel与Title中都保持大写,只有LowerCamel的第一个单词转为小写
+		{"user_ID", "userID", "UserID", "user_id", "USER_ID", "user-id"},
+		{"getHTTP", "getHTTP", "GetHTTP", "get_http", "GET_HTTP", "get-http"},
+		{"parse_URL_query", "parseURLQuery", "ParseURLQuery", "parse_url_query", "PARSE_URL_QUERY", "parse-url-query"},
+		{"FOO_BAR", "fooBAR", "FOOBAR", "foo_bar", "FOO_BAR", "foo-bar"},
+	} {
+		t.Run(tc.in, func(t *testing.T) {
+			if got := LowerCamel(tc.in); got != tc.lowerCamel {
+				t.Errorf("LowerCamel(%q) = %q, want %q", tc.in, got, tc.lowerCamel)
+			}
+			if got := Title(tc.in); got != tc.title {
+				t.Errorf("Title(%q) = %q, want %q", tc.in, got, tc.title)
+			}
+			if got := Snake(tc.in); got != tc.snake {
+				t.Errorf("Snake(%q) = %q, want %q", tc.in, got, tc.snake)
+			}
+			if got := ScreamingSnake(tc.in); got != tc.screamingSnake {
+				t.Errorf("ScreamingSnake(%q) = %q, want %q", tc.in, got, tc.screamingSnake)
+			}
+			if got := Kebab(tc.in); got != tc.kebab {
+				t.Errorf("Kebab(%q) = %q, want %q", tc.in, got, tc.kebab)
+			}
+		})
+	}
+}
+
+func TestJSONName(t *testing.T) {
+	// 期望值与protoc为字段生成的json_name一致
+	for _, tc := range []struct {
+		in   string
+		want string
+	}{
+		{in: "foo", want: "foo"},
+		{in: "foo_bar", want: "fooBar"},
+		{in: "foo_bar_2", want: "fooBar2"},
+		{in: "foo__bar", want: "fooBar"},
+		{in: "_foo", want: "Foo"},
+		{in: "foo_", want: "foo"},
+		{in: "FooBar", want: "FooBar"},
+		{in: "HTTP_server", want: "HTTPServer"},
+	} {
+		if got := JSONName(tc.in); got != tc.want {
+			t.Errorf("JSONName(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestAcronymPolicy(t *testing.T) {
+	// LowerCamel与Title只在第一个单词上不同
+	for _, in := range []string{"HTTPServerID", "user_ID", "getHTTP", "parse_URL_query", "XMLHttpRequest", "id"} {
+		first := Words(in)[0]
+		want := strings.ToLower(first) + strings.TrimPrefix(Title(in), _Capitalize(first))
+		if got := LowerCamel(in); got != want {
+			t.Errorf("LowerCamel(%q) = %q, want %q (Title %q)", in, got, want, Title(in))
+		}
+	}
+}
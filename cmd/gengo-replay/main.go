@@ -0,0 +1,70 @@
+// gengo-replay 将保存下来的CodeGeneratorRequest交给一个protoc插件执行,
+// 并把生成的文件写入指定目录,用于在没有protoc环境的情况下复现插件的问题.
+//
+// 用法:
+//
+//	GENGO_DUMP_REQUEST=/tmp/req.bin protoc --foo_out=. a.proto
+//	gengo-replay -request /tmp/req.bin -plugin ./protoc-gen-foo -out ./out
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/yuansudong/gengo"
+	plugin "github.com/yuansudong/gengo/plugin"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	_RequestPath = flag.String("request", "", "DumpRequest保存的请求文件,以 .json 结尾时按JSON解析")
+	_PluginPath  = flag.String("plugin", "", "要执行的protoc插件")
+	_OutDir      = flag.String("out", ".", "生成文件的输出目录")
+	_Parameter   = flag.String("param", "", "覆盖请求中的插件参数")
+)
+
+func main() {
+	flag.Parse()
+	if err := _Run(); err != nil {
+		fmt.Fprintln(os.Stderr, "gengo-replay:", err)
+		os.Exit(1)
+	}
+}
+
+// _Run 执行插件并写入生成的文件
+func _Run() error {
+	if *_RequestPath == "" || *_PluginPath == "" {
+		flag.Usage()
+		return fmt.Errorf("-request and -plugin are required")
+	}
+	req, err := gengo.ReadRequestFile(*_RequestPath)
+	if err != nil {
+		return err
+	}
+	if *_Parameter != "" {
+		req.Parameter = proto.String(*_Parameter)
+	}
+	// 避免插件再次保存请求覆盖原来的文件
+	gengo.StripDumpRequest(req)
+	input, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+	var stdout bytes.Buffer
+	cmd := exec.Command(*_PluginPath)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), gengo.EnvDumpRequest+"=")
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %v", *_PluginPath, err)
+	}
+	rsp := new(plugin.CodeGeneratorResponse)
+	if err := proto.Unmarshal(stdout.Bytes(), rsp); err != nil {
+		return fmt.Errorf("%s: invalid response: %v", *_PluginPath, err)
+	}
+	return gengo.WriteResponseFiles(*_OutDir, rsp)
+}
@@ -0,0 +1,114 @@
+package gengo
+
+import (
+	"strconv"
+	"strings"
+
+	descriptor "github.com/yuansudong/gengo/descriptor"
+)
+
+// SourceCodeInfo 中各个元素的字段编号,用于计算元素的location path
+const (
+	_FileMessageTag   = 4 // FileDescriptorProto.message_type
+	_FileEnumTag      = 5 // FileDescriptorProto.enum_type
+	_FileServiceTag   = 6 // FileDescriptorProto.service
+	_MessageFieldTag  = 2 // DescriptorProto.field
+	_MessageNestedTag = 3 // DescriptorProto.nested_type
+	_MessageEnumTag   = 4 // DescriptorProto.enum_type
+	_MessageOneofTag  = 8 // DescriptorProto.oneof_decl
+	_EnumValueTag     = 2 // EnumDescriptorProto.value
+	_ServiceMethodTag = 2 // ServiceDescriptorProto.method
+)
+
+// Comments 描述一个元素在proto文件中的注释
+type Comments struct {
+	// Leading 元素前面紧挨着的注释
+	Leading string
+	// Trailing 元素后面的注释
+	Trailing string
+	// LeadingDetached 元素前面与元素之间隔着空行的注释
+	LeadingDetached []string
+}
+
+// IsEmpty 判断是否没有任何注释
+func (c Comments) IsEmpty() bool {
+	return c.Leading == "" && c.Trailing == "" && len(c.LeadingDetached) == 0
+}
+
+// _NewComments 从location中提取注释
+func _NewComments(loc *descriptor.SourceCodeInfo_Location) Comments {
+	if loc == nil {
+		return Comments{}
+	}
+	return Comments{
+		Leading:         loc.GetLeadingComments(),
+		Trailing:        loc.GetTrailingComments(),
+		LeadingDetached: loc.GetLeadingDetachedComments(),
+	}
+}
+
+// _AppendPath 复制base并在末尾追加elems,避免多个元素共用同一个底层数组
+func _AppendPath(base []int32, elems ...int32) []int32 {
+	p := make([]int32, 0, len(base)+len(elems))
+	p = append(p, base...)
+	return append(p, elems...)
+}
+
+// _PathKey 将location path转换为map的键
+func _PathKey(path []int32) string {
+	parts := make([]string, len(path))
+	for i, v := range path {
+		parts[i] = strconv.Itoa(int(v))
+	}
+	return strings.Join(parts, ",")
+}
+
+// Location 根据location path查找SourceCodeInfo中的位置信息,没有时返回nil
+func (f *File) Location(path []int32) *descriptor.SourceCodeInfo_Location {
+	if f._Locations == nil {
+		f._Locations = make(map[string]*descriptor.SourceCodeInfo_Location)
+		for _, loc := range f.GetSourceCodeInfo().GetLocation() {
+			key := _PathKey(loc.GetPath())
+			// 同一个path可能有多个location,第一个是完整的元素
+			if _, ok := f._Locations[key]; !ok {
+				f._Locations[key] = loc
+			}
+		}
+	}
+	return f._Locations[_PathKey(path)]
+}
+
+// Comments 返回消息的注释
+func (m *Message) Comments() Comments {
+	return _NewComments(m.File.Location(m._Path))
+}
+
+// Comments 返回字段的注释
+func (f *Field) Comments() Comments {
+	return _NewComments(f.Message.File.Location(f._Path))
+}
+
+// Comments 返回oneof的注释
+func (o *Oneof) Comments() Comments {
+	return _NewComments(o.Message.File.Location(o._Path))
+}
+
+// Comments 返回枚举的注释
+func (e *Enum) Comments() Comments {
+	return _NewComments(e.File.Location(e._Path))
+}
+
+// Comments 返回枚举值的注释
+func (v *EnumValue) Comments() Comments {
+	return _NewComments(v.Enum.File.Location(v._Path))
+}
+
+// Comments 返回服务的注释
+func (s *Service) Comments() Comments {
+	return _NewComments(s.File.Location(s._Path))
+}
+
+// Comments 返回RPC方法的注释
+func (m *Method) Comments() Comments {
+	return _NewComments(m.Service.File.Location(m._Path))
+}
@@ -0,0 +1,135 @@
+package gengo
+
+import (
+	"reflect"
+	"testing"
+
+	descriptor "github.com/yuansudong/gengo/descriptor"
+	plugin "github.com/yuansudong/gengo/plugin"
+	"google.golang.org/protobuf/proto"
+)
+
+// _Loc 返回path处带有前置注释leading的location
+func _Loc(leading string, path ...int32) *descriptor.SourceCodeInfo_Location {
+	return &descriptor.SourceCodeInfo_Location{
+		Path:            path,
+		Span:            []int32{0, 0, 1},
+		LeadingComments: proto.String(leading),
+	}
+}
+
+// _CommentsFile 返回在_ServiceFile的基础上增加了嵌套类型,oneof,枚举以及注释的文件
+func _CommentsFile() *descriptor.FileDescriptorProto {
+	file := _ServiceFile("c.proto", "c", nil)
+	req := file.MessageType[0]
+	req.Field = append(req.Field, _NamingField("x", 2, 0))
+	req.OneofDecl = []*descriptor.OneofDescriptorProto{{Name: proto.String("choice")}}
+	req.NestedType = []*descriptor.DescriptorProto{{Name: proto.String("Inner")}}
+	req.EnumType = []*descriptor.EnumDescriptorProto{{
+		Name: proto.String("Color"),
+		Value: []*descriptor.EnumValueDescriptorProto{
+			{Name: proto.String("RED"), Number: proto.Int32(0)},
+			{Name: proto.String("BLUE"), Number: proto.Int32(1)},
+		},
+	}}
+	file.EnumType = []*descriptor.EnumDescriptorProto{{
+		Name:  proto.String("Kind"),
+		Value: []*descriptor.EnumValueDescriptorProto{{Name: proto.String("KIND_UNSPECIFIED"), Number: proto.Int32(0)}},
+	}}
+	msg := _Loc(" Req doc\n", 4, 0)
+	msg.TrailingComments = proto.String(" Req trailing\n")
+	msg.LeadingDetachedComments = []string{" detached 1\n", " detached 2\n"}
+	file.SourceCodeInfo = &descriptor.SourceCodeInfo{Location: []*descriptor.SourceCodeInfo_Location{
+		msg,
+		// 同一个path的第二个location只覆盖元素的一部分,不使用它的注释
+		_Loc(" Req name\n", 4, 0),
+		_Loc(" name doc\n", 4, 0, 2, 0),
+		_Loc(" x doc\n", 4, 0, 2, 1),
+		_Loc(" Inner doc\n", 4, 0, 3, 0),
+		_Loc(" Color doc\n", 4, 0, 4, 0),
+		_Loc(" BLUE doc\n", 4, 0, 4, 0, 2, 1),
+		_Loc(" choice doc\n", 4, 0, 8, 0),
+		_Loc(" Kind doc\n", 5, 0),
+		_Loc(" KIND_UNSPECIFIED doc\n", 5, 0, 2, 0),
+		_Loc(" Svc doc\n", 6, 0),
+		_Loc(" Call doc\n", 6, 0, 2, 0),
+	}}
+	return file
+}
+
+func TestComments(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Load(&plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"c.proto"},
+		ProtoFile:      []*descriptor.FileDescriptorProto{_CommentsFile()},
+	}); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	lookupMsg := func(name string) *Message {
+		m, err := r.LookupMsg("", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	lookupEnum := func(name string) *Enum {
+		e, err := r.LookupEnum("", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+	req := lookupMsg(".c.Req")
+	svc, err := r.LookupService(".c.Svc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Comments{Leading: " Req doc\n", Trailing: " Req trailing\n", LeadingDetached: []string{" detached 1\n", " detached 2\n"}}
+	if got := req.Comments(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Req.Comments() = %+v, want %+v", got, want)
+	}
+	for _, tc := range []struct {
+		name    string
+		comment Comments
+		want    string
+	}{
+		{name: "field", comment: req.LookupField("name").Comments(), want: " name doc\n"},
+		{name: "oneof field", comment: req.LookupField("x").Comments(), want: " x doc\n"},
+		{name: "oneof", comment: req.Oneofs[0].Comments(), want: " choice doc\n"},
+		{name: "nested message", comment: lookupMsg(".c.Req.Inner").Comments(), want: " Inner doc\n"},
+		{name: "nested enum", comment: lookupEnum(".c.Req.Color").Comments(), want: " Color doc\n"},
+		{name: "nested enum value", comment: lookupEnum(".c.Req.Color").LookupValue("BLUE").Comments(), want: " BLUE doc\n"},
+		{name: "enum", comment: lookupEnum(".c.Kind").Comments(), want: " Kind doc\n"},
+		{name: "enum value", comment: lookupEnum(".c.Kind").Values[0].Comments(), want: " KIND_UNSPECIFIED doc\n"},
+		{name: "service", comment: svc.Comments(), want: " Svc doc\n"},
+		{name: "method", comment: svc.Methods[0].Comments(), want: " Call doc\n"},
+	} {
+		if tc.comment.Leading != tc.want || tc.comment.Trailing != "" || len(tc.comment.LeadingDetached) != 0 {
+			t.Errorf("%s: Comments() = %+v, want leading %q", tc.name, tc.comment, tc.want)
+		}
+	}
+	// 没有location的元素没有注释
+	if c := lookupEnum(".c.Req.Color").LookupValue("RED").Comments(); !c.IsEmpty() {
+		t.Errorf("RED.Comments() = %+v, want empty", c)
+	}
+	if loc := req.File.Location([]int32{4, 1}); loc != nil {
+		t.Errorf("Location([4 1]) = %v, want nil", loc)
+	}
+}
+
+func TestCommentsWithoutSourceCodeInfo(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Load(&plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"svc.proto"},
+		ProtoFile:      []*descriptor.FileDescriptorProto{_ServiceFile("svc.proto", "svc", nil)},
+	}); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	m, err := r.LookupMethod("svc.Svc.Call")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := m.Comments(); !c.IsEmpty() {
+		t.Errorf("Comments() = %+v, want empty", c)
+	}
+}
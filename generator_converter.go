@@ -0,0 +1,151 @@
+package gengo
+
+import (
+	"fmt"
+
+	descriptor "github.com/yuansudong/gengo/descriptor"
+)
+
+// Base64Encoding 路径参数中bytes字段的base64编码方式
+type Base64Encoding int
+
+const (
+	// Base64Std 标准base64编码,对应 base64.StdEncoding
+	Base64Std Base64Encoding = iota
+	// Base64URL URL安全的base64编码,对应 base64.URLEncoding
+	Base64URL
+)
+
+// String 返回编码方式的名称
+func (e Base64Encoding) String() string {
+	if e == Base64URL {
+		return "url"
+	}
+	return "std"
+}
+
+// ConverterSet 描述把路径参数的字符串转换为字段值的一组函数.
+// 所有函数都位于Package包中,表中只记录函数名,
+// 生成的代码通过 Parameter.ConvertFuncExpr 引用它们.
+type ConverterSet struct {
+	// Package 转换函数所在的包, Name为空时由PackageNameFromPath推断
+	Package GoPackage
+	// Proto3 proto3单值字段的转换函数,按字段类型索引
+	Proto3 map[descriptor.FieldDescriptorProto_Type]string
+	// Proto3Repeated proto3 repeated字段的转换函数
+	Proto3Repeated map[descriptor.FieldDescriptorProto_Type]string
+	// Proto2 proto2单值字段以及proto3 optional字段的转换函数,返回指针
+	Proto2 map[descriptor.FieldDescriptorProto_Type]string
+	// Proto2Repeated proto2 repeated字段的转换函数
+	Proto2Repeated map[descriptor.FieldDescriptorProto_Type]string
+	// WellKnown well-known类型的转换函数,按完整的消息名索引,例如 .google.protobuf.Timestamp
+	WellKnown map[string]string
+	// BytesEncoding bytes字段的base64编码方式. 为Base64Std时使用上面表中的函数,
+	// 为Base64URL时单值与repeated的bytes字段分别使用URLSafeBytes与URLSafeBytesSlice,
+	// 没有设置这两个函数时ConvertFuncExpr返回错误.
+	BytesEncoding Base64Encoding
+	// URLSafeBytes 只接受URL安全编码的单个bytes字段的转换函数
+	URLSafeBytes string
+	// URLSafeBytesSlice 只接受URL安全编码的repeated bytes字段的转换函数
+	URLSafeBytesSlice string
+}
+
+// DefaultConverterSet 返回使用grpc-gateway runtime包的转换函数集合.
+// 每次调用都返回新的副本,调用方可以在此基础上修改.
+// runtime.Bytes 与 runtime.BytesSlice 在标准编码解码失败时会再按URL安全编码解码,
+// 已经同时接受两种编码; runtime包没有只接受URL安全编码的函数,所以这里不设置URLSafeBytes.
+func DefaultConverterSet() *ConverterSet {
+	return &ConverterSet{
+		Package:        _DefaultConverterPackage,
+		Proto3:         _CopyTypeFuncs(_Proto3ConvertFuncs),
+		Proto3Repeated: _CopyTypeFuncs(_Proto3RepeatedConvertFuncs),
+		Proto2:         _CopyTypeFuncs(_Proto2ConvertFuncs),
+		Proto2Repeated: _CopyTypeFuncs(_Proto2RepeatedConvertFuncs),
+		WellKnown:      _CopyNameFuncs(_WellKnownTypeConv),
+		BytesEncoding:  Base64Std,
+	}
+}
+
+// IsWellKnownType 判断typeName是否是这个集合能够转换的well-known类型
+func (c *ConverterSet) IsWellKnownType(typeName string) bool {
+	_, ok := c.WellKnown[typeName]
+	return ok
+}
+
+// _Func 返回参数p使用的转换函数名
+func (c *ConverterSet) _Func(p Parameter) (string, error) {
+	if p.Target.IsMap() {
+		return "", fmt.Errorf("map type %s of parameter %s in %s.%s is not supported", p.Target.GetTypeName(), p.FieldPath, p.Method.Service.GetName(), p.Method.GetName())
+	}
+	typ := p.Target.GetType()
+	tbl := c.Proto3
+	switch {
+	case p.IsProto2() && p.IsRepeated():
+		tbl = c.Proto2Repeated
+	case p.IsProto2():
+		tbl = c.Proto2
+	case p.IsRepeated():
+		tbl = c.Proto3Repeated
+	case p.IsProto3Optional() && typ != descriptor.FieldDescriptorProto_TYPE_BYTES:
+		// proto3 optional 字段在Go中是指针类型,与proto2使用同样的转换函数,
+		// bytes字段本身可以为nil,不需要指针.
+		tbl = c.Proto2
+	}
+	if typ == descriptor.FieldDescriptorProto_TYPE_BYTES && c.BytesEncoding == Base64URL {
+		conv := c.URLSafeBytes
+		if p.IsRepeated() {
+			conv = c.URLSafeBytesSlice
+		}
+		if conv == "" {
+			return "", fmt.Errorf("no URL-safe bytes converter in %s for parameter %s in %s.%s", c.Package.Path, p.FieldPath, p.Method.Service.GetName(), p.Method.GetName())
+		}
+		return conv, nil
+	}
+	conv, ok := tbl[typ]
+	if !ok {
+		conv, ok = c.WellKnown[p.Target.GetTypeName()]
+	}
+	if !ok {
+		return "", fmt.Errorf("unsupported field type %s of parameter %s in %s.%s", typ, p.FieldPath, p.Method.Service.GetName(), p.Method.GetName())
+	}
+	return conv, nil
+}
+
+// SetConverterSet 设置路径参数使用的转换函数集合,没有设置或者c为nil时使用DefaultConverterSet.
+// 在Load之后调用时,已经加载的路径参数也会改用c,但是Load只按照当时的集合检查well-known类型.
+func (r *Registry) SetConverterSet(c *ConverterSet) {
+	if c == nil {
+		c = DefaultConverterSet()
+	}
+	r._Converters = c
+	for _, m := range r._Methods {
+		for _, b := range m.Bindings {
+			for i := range b.PathParams {
+				b.PathParams[i].Converters = c
+			}
+		}
+	}
+}
+
+// ConverterSet 返回路径参数使用的转换函数集合
+func (r *Registry) ConverterSet() *ConverterSet {
+	return r._Converters
+}
+
+// _CopyTypeFuncs 复制按字段类型索引的函数表
+func _CopyTypeFuncs(src map[descriptor.FieldDescriptorProto_Type]string) map[descriptor.FieldDescriptorProto_Type]string {
+	dst := make(map[descriptor.FieldDescriptorProto_Type]string, len(src))
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
+
+// _CopyNameFuncs 复制按消息名索引的函数表
+func _CopyNameFuncs(src map[string]string) map[string]string {
+	dst := make(map[string]string, len(src))
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
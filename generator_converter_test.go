@@ -0,0 +1,302 @@
+package gengo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	descriptor "github.com/yuansudong/gengo/descriptor"
+	plugin "github.com/yuansudong/gengo/plugin"
+	"google.golang.org/protobuf/proto"
+)
+
+// _ScalarTypes 是可以作为路径参数的标量类型
+var _ScalarTypes = []descriptor.FieldDescriptorProto_Type{
+	descriptor.FieldDescriptorProto_TYPE_DOUBLE,
+	descriptor.FieldDescriptorProto_TYPE_FLOAT,
+	descriptor.FieldDescriptorProto_TYPE_INT64,
+	descriptor.FieldDescriptorProto_TYPE_UINT64,
+	descriptor.FieldDescriptorProto_TYPE_INT32,
+	descriptor.FieldDescriptorProto_TYPE_FIXED64,
+	descriptor.FieldDescriptorProto_TYPE_FIXED32,
+	descriptor.FieldDescriptorProto_TYPE_BOOL,
+	descriptor.FieldDescriptorProto_TYPE_STRING,
+	descriptor.FieldDescriptorProto_TYPE_BYTES,
+	descriptor.FieldDescriptorProto_TYPE_UINT32,
+	descriptor.FieldDescriptorProto_TYPE_ENUM,
+	descriptor.FieldDescriptorProto_TYPE_SFIXED32,
+	descriptor.FieldDescriptorProto_TYPE_SFIXED64,
+	descriptor.FieldDescriptorProto_TYPE_SINT32,
+	descriptor.FieldDescriptorProto_TYPE_SINT64,
+}
+
+// _FieldKind 描述字段的声明方式
+type _FieldKind string
+
+const (
+	_Singular       _FieldKind = "singular"
+	_Repeated       _FieldKind = "repeated"
+	_Proto3Optional _FieldKind = "optional"
+)
+
+// _ConverterFieldName 返回测试文件中对应类型和声明方式的字段名
+func _ConverterFieldName(typ descriptor.FieldDescriptorProto_Type, kind _FieldKind) string {
+	return fmt.Sprintf("%s_%s", strings.ToLower(strings.TrimPrefix(typ.String(), "TYPE_")), kind)
+}
+
+// _ConverterFile 返回一个包含所有标量类型字段的文件, syntax 为 proto2 或 proto3
+func _ConverterFile(syntax string) *descriptor.FileDescriptorProto {
+	pkg := "conv" + strings.TrimPrefix(syntax, "proto")
+	msg := &descriptor.DescriptorProto{Name: proto.String("Req")}
+	kinds := []_FieldKind{_Singular, _Repeated}
+	if syntax == "proto3" {
+		kinds = append(kinds, _Proto3Optional)
+	}
+	for _, typ := range _ScalarTypes {
+		for _, kind := range kinds {
+			f := &descriptor.FieldDescriptorProto{
+				Name:   proto.String(_ConverterFieldName(typ, kind)),
+				Number: proto.Int32(int32(len(msg.Field) + 1)),
+				Type:   typ.Enum(),
+				Label:  descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			}
+			switch kind {
+			case _Repeated:
+				f.Label = descriptor.FieldDescriptorProto_LABEL_REPEATED.Enum()
+			case _Proto3Optional:
+				f.Proto3Optional = proto.Bool(true)
+				f.OneofIndex = proto.Int32(int32(len(msg.OneofDecl)))
+				msg.OneofDecl = append(msg.OneofDecl, &descriptor.OneofDescriptorProto{Name: proto.String("_" + f.GetName())})
+			}
+			if typ == descriptor.FieldDescriptorProto_TYPE_ENUM {
+				f.TypeName = proto.String("." + pkg + ".Kind")
+			}
+			msg.Field = append(msg.Field, f)
+		}
+	}
+	fd := &descriptor.FileDescriptorProto{
+		Name:        proto.String(pkg + ".proto"),
+		Package:     proto.String(pkg),
+		Syntax:      proto.String(syntax),
+		Options:     &descriptor.FileOptions{GoPackage: proto.String("example.com/" + pkg)},
+		MessageType: []*descriptor.DescriptorProto{msg},
+		EnumType: []*descriptor.EnumDescriptorProto{{
+			Name:  proto.String("Kind"),
+			Value: []*descriptor.EnumValueDescriptorProto{{Name: proto.String("KIND_UNSPECIFIED"), Number: proto.Int32(0)}},
+		}},
+		Service: []*descriptor.ServiceDescriptorProto{{
+			Name: proto.String("Svc"),
+			Method: []*descriptor.MethodDescriptorProto{{
+				Name:       proto.String("Call"),
+				InputType:  proto.String("." + pkg + ".Req"),
+				OutputType: proto.String("." + pkg + ".Req"),
+			}},
+		}},
+	}
+	return fd
+}
+
+// _ConverterParam 返回syntax文件中对应字段的路径参数
+func _ConverterParam(t *testing.T, r *Registry, syntax string, typ descriptor.FieldDescriptorProto_Type, kind _FieldKind) Parameter {
+	t.Helper()
+	pkg := "conv" + strings.TrimPrefix(syntax, "proto")
+	meth, err := r.LookupMethod(pkg + ".Svc.Call")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := _ConverterFieldName(typ, kind)
+	for _, f := range meth.RequestType.Fields {
+		if f.GetName() == name {
+			return Parameter{
+				FieldPath: FieldPath{{Name: name, Target: f}},
+				Target:    f,
+				Method:    meth,
+			}
+		}
+	}
+	t.Fatalf("no field %s in %s", name, pkg)
+	return Parameter{}
+}
+
+func TestConvertFuncExpr(t *testing.T) {
+	r := NewRegistry()
+	r.SetMultiPackage(true)
+	if err := r.Load(&plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"conv2.proto", "conv3.proto"},
+		ProtoFile:      []*descriptor.FileDescriptorProto{_ConverterFile("proto2"), _ConverterFile("proto3")},
+	}); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	for _, tc := range []struct {
+		typ                               descriptor.FieldDescriptorProto_Type
+		proto3, proto3Repeated, proto3Opt string
+		proto2, proto2Repeated            string
+	}{
+		{descriptor.FieldDescriptorProto_TYPE_DOUBLE, "Float64", "Float64Slice", "Float64P", "Float64P", "Float64Slice"},
+		{descriptor.FieldDescriptorProto_TYPE_FLOAT, "Float32", "Float32Slice", "Float32P", "Float32P", "Float32Slice"},
+		{descriptor.FieldDescriptorProto_TYPE_INT64, "Int64", "Int64Slice", "Int64P", "Int64P", "Int64Slice"},
+		{descriptor.FieldDescriptorProto_TYPE_UINT64, "Uint64", "Uint64Slice", "Uint64P", "Uint64P", "Uint64Slice"},
+		{descriptor.FieldDescriptorProto_TYPE_INT32, "Int32", "Int32Slice", "Int32P", "Int32P", "Int32Slice"},
+		{descriptor.FieldDescriptorProto_TYPE_FIXED64, "Uint64", "Uint64Slice", "Uint64P", "Uint64P", "Uint64Slice"},
+		{descriptor.FieldDescriptorProto_TYPE_FIXED32, "Uint32", "Uint32Slice", "Uint32P", "Uint32P", "Uint32Slice"},
+		{descriptor.FieldDescriptorProto_TYPE_BOOL, "Bool", "BoolSlice", "BoolP", "BoolP", "BoolSlice"},
+		{descriptor.FieldDescriptorProto_TYPE_STRING, "String", "StringSlice", "StringP", "StringP", "StringSlice"},
+		// bytes在Go中是 []byte,proto2与proto3 optional都不需要指针
+		{descriptor.FieldDescriptorProto_TYPE_BYTES, "Bytes", "BytesSlice", "Bytes", "Bytes", "BytesSlice"},
+		{descriptor.FieldDescriptorProto_TYPE_UINT32, "Uint32", "Uint32Slice", "Uint32P", "Uint32P", "Uint32Slice"},
+		{descriptor.FieldDescriptorProto_TYPE_ENUM, "Enum", "EnumSlice", "EnumP", "EnumP", "EnumSlice"},
+		{descriptor.FieldDescriptorProto_TYPE_SFIXED32, "Int32", "Int32Slice", "Int32P", "Int32P", "Int32Slice"},
+		{descriptor.FieldDescriptorProto_TYPE_SFIXED64, "Int64", "Int64Slice", "Int64P", "Int64P", "Int64Slice"},
+		{descriptor.FieldDescriptorProto_TYPE_SINT32, "Int32", "Int32Slice", "Int32P", "Int32P", "Int32Slice"},
+		{descriptor.FieldDescriptorProto_TYPE_SINT64, "Int64", "Int64Slice", "Int64P", "Int64P", "Int64Slice"},
+	} {
+		for _, c := range []struct {
+			syntax string
+			kind   _FieldKind
+			want   string
+		}{
+			{"proto3", _Singular, tc.proto3},
+			{"proto3", _Repeated, tc.proto3Repeated},
+			{"proto3", _Proto3Optional, tc.proto3Opt},
+			{"proto2", _Singular, tc.proto2},
+			{"proto2", _Repeated, tc.proto2Repeated},
+		} {
+			t.Run(fmt.Sprintf("%s/%s/%s", c.syntax, tc.typ, c.kind), func(t *testing.T) {
+				p := _ConverterParam(t, r, c.syntax, tc.typ, c.kind)
+				expr, pkg, err := p.ConvertFuncExpr()
+				if err != nil {
+					t.Fatalf("ConvertFuncExpr() failed: %v", err)
+				}
+				if want := "runtime." + c.want; expr != want {
+					t.Errorf("ConvertFuncExpr() = %q, want %q", expr, want)
+				}
+				if pkg.Path != "github.com/grpc-ecosystem/grpc-gateway/runtime" {
+					t.Errorf("ConvertFuncExpr() package = %q, want the grpc-gateway runtime", pkg.Path)
+				}
+			})
+		}
+	}
+}
+
+func TestConvertFuncExprBytesEncoding(t *testing.T) {
+	r := NewRegistry()
+	r.SetMultiPackage(true)
+	if err := r.Load(&plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"conv2.proto", "conv3.proto"},
+		ProtoFile:      []*descriptor.FileDescriptorProto{_ConverterFile("proto2"), _ConverterFile("proto3")},
+	}); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	custom := func(enc Base64Encoding) *ConverterSet {
+		c := DefaultConverterSet()
+		c.Package = GoPackage{Path: "example.com/rt", Name: "rt"}
+		c.BytesEncoding = enc
+		c.URLSafeBytes = "URLBytes"
+		c.URLSafeBytesSlice = "URLBytesSlice"
+		return c
+	}
+	for _, tc := range []struct {
+		name     string
+		set      *ConverterSet
+		single   string
+		repeated string
+	}{
+		{name: "default std", set: DefaultConverterSet(), single: "runtime.Bytes", repeated: "runtime.BytesSlice"},
+		{name: "custom std", set: custom(Base64Std), single: "rt.Bytes", repeated: "rt.BytesSlice"},
+		{name: "custom url", set: custom(Base64URL), single: "rt.URLBytes", repeated: "rt.URLBytesSlice"},
+	} {
+		for _, syntax := range []string{"proto2", "proto3"} {
+			for _, kind := range []_FieldKind{_Singular, _Repeated} {
+				t.Run(fmt.Sprintf("%s/%s/%s", tc.name, syntax, kind), func(t *testing.T) {
+					p := _ConverterParam(t, r, syntax, descriptor.FieldDescriptorProto_TYPE_BYTES, kind)
+					p.Converters = tc.set
+					want := tc.single
+					if kind == _Repeated {
+						want = tc.repeated
+					}
+					if expr, _, err := p.ConvertFuncExpr(); err != nil || expr != want {
+						t.Errorf("ConvertFuncExpr() = %q, %v; want %q", expr, err, want)
+					}
+				})
+			}
+		}
+	}
+
+	// 默认集合没有只接受URL安全编码的函数,选择Base64URL时报错而不是静默使用runtime.Bytes
+	url := DefaultConverterSet()
+	url.BytesEncoding = Base64URL
+	p := _ConverterParam(t, r, "proto3", descriptor.FieldDescriptorProto_TYPE_BYTES, _Singular)
+	p.Converters = url
+	if expr, _, err := p.ConvertFuncExpr(); err == nil {
+		t.Errorf("ConvertFuncExpr() with Base64URL and no URL-safe converter = %q, want error", expr)
+	}
+	// 其他类型不受BytesEncoding影响
+	p = _ConverterParam(t, r, "proto3", descriptor.FieldDescriptorProto_TYPE_STRING, _Singular)
+	p.Converters = url
+	if expr, _, err := p.ConvertFuncExpr(); err != nil || expr != "runtime.String" {
+		t.Errorf("ConvertFuncExpr() = %q, %v; want runtime.String", expr, err)
+	}
+}
+
+func TestSetConverterSet(t *testing.T) {
+	file := _ServiceFile("svc.proto", "svc", _HTTPOption(_HTTPRuleGet, "/v1/{name}", ""))
+	r := NewRegistry()
+	if err := r.Load(&plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"svc.proto"},
+		ProtoFile:      []*descriptor.FileDescriptorProto{file},
+	}); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	meth, err := r.LookupMethod("svc.Svc.Call")
+	if err != nil {
+		t.Fatal(err)
+	}
+	param := func() Parameter { return meth.Bindings[0].PathParams[0] }
+	if expr, _, err := param().ConvertFuncExpr(); err != nil || expr != "runtime.String" {
+		t.Errorf("ConvertFuncExpr() = %q, %v; want runtime.String", expr, err)
+	}
+
+	// Load之后设置的集合同样作用于已经加载的参数
+	custom := DefaultConverterSet()
+	custom.Package = GoPackage{Path: "example.com/rt/v2", Name: "rt"}
+	r.SetConverterSet(custom)
+	expr, pkg, err := param().ConvertFuncExpr()
+	if err != nil || expr != "rt.String" || pkg.Path != "example.com/rt/v2" {
+		t.Errorf("ConvertFuncExpr() = %q, %s, %v; want rt.String from example.com/rt/v2", expr, pkg.Path, err)
+	}
+	im := NewImportManager(GoPackage{Path: "example.com/out", Name: "out"})
+	im.Reserve("rt")
+	if expr, err := param().ConvertFuncExprIn(im); err != nil || expr != "rt1.String" {
+		t.Errorf("ConvertFuncExprIn() = %q, %v; want rt1.String", expr, err)
+	}
+
+	// 只设置了Path的包,包名由导入路径推断
+	pathOnly := DefaultConverterSet()
+	pathOnly.Package = GoPackage{Path: "example.com/conv/v3"}
+	r.SetConverterSet(pathOnly)
+	expr, pkg, err = param().ConvertFuncExpr()
+	if err != nil || expr != "conv.String" || pkg.Name != "conv" || pkg.Path != "example.com/conv/v3" {
+		t.Errorf("ConvertFuncExpr() = %q, %+v, %v; want conv.String from example.com/conv/v3", expr, pkg, err)
+	}
+	if expr, err := param().ConvertFuncExprIn(NewImportManager(GoPackage{Path: "example.com/out", Name: "out"})); err != nil || expr != "conv.String" {
+		t.Errorf("ConvertFuncExprIn() = %q, %v; want conv.String", expr, err)
+	}
+
+	r.SetConverterSet(nil)
+	if expr, _, err := param().ConvertFuncExpr(); err != nil || expr != "runtime.String" {
+		t.Errorf("ConvertFuncExpr() after SetConverterSet(nil) = %q, %v; want runtime.String", expr, err)
+	}
+}
+
+func TestDefaultConverterSetIsCopy(t *testing.T) {
+	a := DefaultConverterSet()
+	a.Proto3[descriptor.FieldDescriptorProto_TYPE_STRING] = "MyString"
+	a.WellKnown[".google.protobuf.Timestamp"] = "MyTimestamp"
+	b := DefaultConverterSet()
+	if got := b.Proto3[descriptor.FieldDescriptorProto_TYPE_STRING]; got != "String" {
+		t.Errorf("DefaultConverterSet() shares tables: Proto3[STRING] = %q", got)
+	}
+	if got := b.WellKnown[".google.protobuf.Timestamp"]; got != "Timestamp" {
+		t.Errorf("DefaultConverterSet() shares tables: WellKnown[Timestamp] = %q", got)
+	}
+}
@@ -0,0 +1,172 @@
+package gengo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity 描述诊断信息的严重程度
+type Severity int
+
+const (
+	// SeverityError 错误,会导致生成失败
+	SeverityError Severity = iota
+	// SeverityWarning 警告,不影响生成
+	SeverityWarning
+)
+
+// String 返回严重程度的名称
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// Element 是可以定位到proto源码位置的元素,
+// File, Message, Field, Oneof, Enum, EnumValue, Service 和 Method 都实现了这个接口
+type Element interface {
+	// _Source 返回元素所在的文件,location path以及完整名称
+	_Source() (*File, []int32, string)
+}
+
+// _Location 是还没有创建对应包装类型时使用的元素位置
+type _Location struct {
+	file *File
+	path []int32
+	fqn  string
+}
+
+func (l _Location) _Source() (*File, []int32, string) { return l.file, l.path, l.fqn }
+
+func (f *File) _Source() (*File, []int32, string) { return f, nil, f.GetName() }
+
+func (m *Message) _Source() (*File, []int32, string) { return m.File, m._Path, m.FQMN() }
+
+func (f *Field) _Source() (*File, []int32, string) {
+	return f.Message.File, f._Path, f.Message.FQMN() + "." + f.GetName()
+}
+
+func (o *Oneof) _Source() (*File, []int32, string) { return o.Message.File, o._Path, o.FQN() }
+
+func (e *Enum) _Source() (*File, []int32, string) { return e.File, e._Path, e.FQEN() }
+
+func (v *EnumValue) _Source() (*File, []int32, string) { return v.Enum.File, v._Path, v.FQN() }
+
+func (s *Service) _Source() (*File, []int32, string) { return s.File, s._Path, s.FQSN() }
+
+func (m *Method) _Source() (*File, []int32, string) { return m.Service.File, m._Path, m.FQMN() }
+
+// Diagnostic 是一条带有proto源码位置的诊断信息
+type Diagnostic struct {
+	// File proto文件名
+	File string
+	// Line 行号,从1开始,没有位置信息时为0
+	Line int
+	// Column 列号,从1开始,没有位置信息时为0
+	Column int
+	// Element 元素的完整名称
+	Element string
+	// Severity 严重程度
+	Severity Severity
+	// Message 诊断信息
+	Message string
+}
+
+// NewDiagnostic 创建一条关于elem的诊断信息,位置从文件的SourceCodeInfo中获取
+func NewDiagnostic(elem Element, severity Severity, format string, args ...interface{}) *Diagnostic {
+	file, path, fqn := elem._Source()
+	d := &Diagnostic{
+		File:     file.GetName(),
+		Element:  fqn,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if span := file.Location(path).GetSpan(); len(span) >= 2 {
+		d.Line = int(span[0]) + 1
+		d.Column = int(span[1]) + 1
+	}
+	return d
+}
+
+// _AsDiagnostic 将err转换为关于elem的错误诊断,err本身是诊断时原样返回
+func _AsDiagnostic(elem Element, err error) *Diagnostic {
+	if d, ok := err.(*Diagnostic); ok {
+		return d
+	}
+	return NewDiagnostic(elem, SeverityError, "%v", err)
+}
+
+// Error 按protoc的格式输出,例如 foo.proto:12:3: no message found: .foo.Bar
+func (d *Diagnostic) Error() string {
+	msg := d.Message
+	if d.Severity == SeverityWarning {
+		msg = "warning: " + msg
+	}
+	switch {
+	case d.File == "":
+		return msg
+	case d.Line == 0:
+		return fmt.Sprintf("%s: %s", d.File, msg)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Column, msg)
+}
+
+// Diagnostics 收集多条诊断信息,而不是在第一个错误时停止
+type Diagnostics []*Diagnostic
+
+// Add 增加一条诊断信息
+func (ds *Diagnostics) Add(d *Diagnostic) {
+	*ds = append(*ds, d)
+}
+
+// Errorf 增加一条关于elem的错误
+func (ds *Diagnostics) Errorf(elem Element, format string, args ...interface{}) {
+	ds.Add(NewDiagnostic(elem, SeverityError, format, args...))
+}
+
+// Warnf 增加一条关于elem的警告
+func (ds *Diagnostics) Warnf(elem Element, format string, args ...interface{}) {
+	ds.Add(NewDiagnostic(elem, SeverityWarning, format, args...))
+}
+
+// Errors 返回所有错误级别的诊断信息
+func (ds Diagnostics) Errors() Diagnostics {
+	return ds._Filter(SeverityError)
+}
+
+// Warnings 返回所有警告级别的诊断信息
+func (ds Diagnostics) Warnings() Diagnostics {
+	return ds._Filter(SeverityWarning)
+}
+
+// _Filter 按严重程度过滤
+func (ds Diagnostics) _Filter(severity Severity) Diagnostics {
+	var out Diagnostics
+	for _, d := range ds {
+		if d.Severity == severity {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// Err 没有错误时返回nil,否则返回所有错误
+func (ds Diagnostics) Err() error {
+	if errs := ds.Errors(); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Error 每行输出一条诊断信息
+func (ds Diagnostics) Error() string {
+	msgs := make([]string, 0, len(ds))
+	for _, d := range ds {
+		msgs = append(msgs, d.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
@@ -0,0 +1,125 @@
+package gengo
+
+import (
+	"testing"
+
+	descriptor "github.com/yuansudong/gengo/descriptor"
+	plugin "github.com/yuansudong/gengo/plugin"
+	"google.golang.org/protobuf/proto"
+)
+
+// _SpanLoc 返回path处范围为span的location, span中的行号和列号从0开始
+func _SpanLoc(span []int32, path ...int32) *descriptor.SourceCodeInfo_Location {
+	return &descriptor.SourceCodeInfo_Location{Path: path, Span: span}
+}
+
+func TestNewDiagnostic(t *testing.T) {
+	file := _ServiceFile("svc.proto", "svc", nil)
+	file.SourceCodeInfo = &descriptor.SourceCodeInfo{Location: []*descriptor.SourceCodeInfo_Location{
+		// 单行元素的span是 [行, 起始列, 结束列]
+		_SpanLoc([]int32{11, 2, 20}, 4, 0, 2, 0),
+		// 多行元素的span是 [起始行, 起始列, 结束行, 结束列]
+		_SpanLoc([]int32{9, 0, 12, 1}, 4, 0),
+		// span不完整的location没有位置信息
+		_SpanLoc([]int32{5}, 6, 0),
+	}}
+	r := NewRegistry()
+	if err := r.Load(&plugin.CodeGeneratorRequest{FileToGenerate: []string{"svc.proto"}, ProtoFile: []*descriptor.FileDescriptorProto{file}}); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	req, err := r.LookupMsg("", ".svc.Req")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc, err := r.LookupService(".svc.Svc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := r.LookupFile("svc.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name     string
+		elem     Element
+		severity Severity
+		element  string
+		want     string
+	}{
+		{name: "field", elem: req.LookupField("name"), severity: SeverityError, element: ".svc.Req.name", want: "svc.proto:12:3: bad field"},
+		{name: "multi-line message", elem: req, severity: SeverityError, element: ".svc.Req", want: "svc.proto:10:1: bad field"},
+		{name: "warning", elem: req, severity: SeverityWarning, element: ".svc.Req", want: "svc.proto:10:1: warning: bad field"},
+		{name: "short span", elem: svc, severity: SeverityError, element: ".svc.Svc", want: "svc.proto: bad field"},
+		{name: "no location", elem: svc.Methods[0], severity: SeverityError, element: ".svc.Svc.Call", want: "svc.proto: bad field"},
+		{name: "file", elem: f, severity: SeverityError, element: "svc.proto", want: "svc.proto: bad field"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			d := NewDiagnostic(tc.elem, tc.severity, "bad %s", "field")
+			if d.Element != tc.element || d.Severity != tc.severity {
+				t.Errorf("NewDiagnostic() = %+v, want element %s severity %s", d, tc.element, tc.severity)
+			}
+			if got := d.Error(); got != tc.want {
+				t.Errorf("Error() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+	if got := (&Diagnostic{Message: "no such file"}).Error(); got != "no such file" {
+		t.Errorf("Error() without file = %q", got)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	var ds Diagnostics
+	if ds.Err() != nil {
+		t.Errorf("Err() of empty diagnostics = %v, want nil", ds.Err())
+	}
+	ds.Add(&Diagnostic{File: "a.proto", Line: 3, Column: 5, Severity: SeverityWarning, Message: "w"})
+	if ds.Err() != nil {
+		t.Errorf("Err() with only warnings = %v, want nil", ds.Err())
+	}
+	ds.Add(&Diagnostic{File: "a.proto", Line: 4, Column: 1, Severity: SeverityError, Message: "e1"})
+	ds.Add(&Diagnostic{File: "b.proto", Severity: SeverityError, Message: "e2"})
+	if len(ds.Warnings()) != 1 || len(ds.Errors()) != 2 {
+		t.Errorf("Warnings() = %d, Errors() = %d; want 1, 2", len(ds.Warnings()), len(ds.Errors()))
+	}
+	if got, want := ds.Error(), "a.proto:3:5: warning: w\na.proto:4:1: e1\nb.proto: e2"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	// Err只包含错误
+	if got, want := ds.Err().Error(), "a.proto:4:1: e1\nb.proto: e2"; got != want {
+		t.Errorf("Err() = %q, want %q", got, want)
+	}
+	for s, want := range map[Severity]string{SeverityError: "error", SeverityWarning: "warning", Severity(7): "Severity(7)"} {
+		if s.String() != want {
+			t.Errorf("Severity(%d).String() = %q, want %q", int(s), s.String(), want)
+		}
+	}
+}
+
+func TestLoadReportsSourcePositions(t *testing.T) {
+	file := &descriptor.FileDescriptorProto{
+		Name:    proto.String("pos.proto"),
+		Package: proto.String("pos"),
+		Syntax:  proto.String("proto3"),
+		Options: &descriptor.FileOptions{GoPackage: proto.String("example.com/pos")},
+		MessageType: []*descriptor.DescriptorProto{{
+			Name: proto.String("Msg"),
+			Field: []*descriptor.FieldDescriptorProto{
+				_TypedField("a", 1, descriptor.FieldDescriptorProto_LABEL_OPTIONAL, descriptor.FieldDescriptorProto_TYPE_MESSAGE, ".pos.Missing"),
+				_TypedField("b", 2, descriptor.FieldDescriptorProto_LABEL_OPTIONAL, descriptor.FieldDescriptorProto_TYPE_ENUM, ".pos.Gone"),
+			},
+		}},
+		SourceCodeInfo: &descriptor.SourceCodeInfo{Location: []*descriptor.SourceCodeInfo_Location{
+			_SpanLoc([]int32{4, 2, 20}, 4, 0, 2, 0),
+			_SpanLoc([]int32{5, 4, 18}, 4, 0, 2, 1),
+		}},
+	}
+	err := NewRegistry().Load(&plugin.CodeGeneratorRequest{FileToGenerate: []string{"pos.proto"}, ProtoFile: []*descriptor.FileDescriptorProto{file}})
+	want := "pos.proto:5:3: no message found: .pos.Missing\npos.proto:6:5: no enum found: .pos.Gone"
+	if err == nil || err.Error() != want {
+		t.Errorf("Load() = %v, want\n%s", err, want)
+	}
+	if _, ok := err.(Diagnostics); !ok {
+		t.Errorf("Load() returned %T, want Diagnostics", err)
+	}
+}
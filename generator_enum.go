@@ -15,6 +15,10 @@ type Enum struct {
 	Outers []string
 	*descriptor.EnumDescriptorProto
 	Index int
+	// Values 枚举的所有值,按定义的顺序排列
+	Values []*EnumValue
+	// _Path 枚举在SourceCodeInfo中的location path
+	_Path []int32
 }
 
 // FQEN 返回完整的枚举名称.
@@ -28,19 +32,78 @@ func (e *Enum) FQEN() string {
 	return strings.Join(components, ".")
 }
 
-// GoType 返回一个与go相关的类型
-func (e *Enum) GoType(currentPackage string) string {
-	var components []string
-	components = append(components, e.Outers...)
-	components = append(components, e.GetName())
+// GoName 返回不带包名的Go类型名,与protoc-gen-go的命名一致
+func (e *Enum) GoName() string {
+	return GoCamelCase(_RelativeName(e.Outers, e.GetName()))
+}
 
-	name := strings.Join(components, "_")
+// GoType 返回在currentPackage包中引用这个枚举的Go类型,
+// 包名与Registry.NewGeneratedFile创建的生成文件一致,见 Message.GoType
+func (e *Enum) GoType(currentPackage string) string {
+	name := e.GoName()
 	if e.File.GoPkg.Path == currentPackage {
 		return name
 	}
-	pkg := e.File.GoPkg.Name
-	if alias := e.File.GoPkg.Alias; alias != "" {
-		pkg = alias
+	return fmt.Sprintf("%s.%s", e.File.GoPkg._LocalName(), name)
+}
+
+// GoTypeIn 返回在使用im管理导入的生成文件中引用这个枚举的Go类型,
+// 会同时在im中记录枚举所在的包,保证限定名与渲染出的导入一致
+func (e *Enum) GoTypeIn(im *ImportManager) string {
+	return im.Qualify(e.File.GoPkg, e.GoName())
+}
+
+// EnumValue 描述枚举中的一个值
+type EnumValue struct {
+	// Enum 这个值属于哪个枚举
+	Enum *Enum
+	*descriptor.EnumValueDescriptorProto
+	// Index 在枚举的值列表中的下标
+	Index int
+	// AliasOf 之前已有相同编号的值时,指向第一个使用这个编号的值.
+	// 枚举没有设置allow_alias时Registry.Load会把这种重复报告为错误.
+	AliasOf *EnumValue
+	// _Path 枚举值在SourceCodeInfo中的location path
+	_Path []int32
+}
+
+// FQN 返回枚举值的完整名称,与proto的作用域规则一致,枚举值与枚举处于同一个作用域
+func (v *EnumValue) FQN() string {
+	fqen := v.Enum.FQEN()
+	return fqen[:len(fqen)-len(v.Enum.GetName())] + v.GetName()
+}
+
+// GoName 返回protoc-gen-go为枚举值生成的常量名
+func (v *EnumValue) GoName() string {
+	return v.Enum.ValueGoName(v.GetName())
+}
+
+// Deprecated 判断枚举值是否被标记为废弃
+func (v *EnumValue) Deprecated() bool {
+	return v.GetOptions().GetDeprecated()
+}
+
+// IsAlias 判断枚举值是否是另一个值的别名
+func (v *EnumValue) IsAlias() bool {
+	return v.AliasOf != nil
+}
+
+// LookupValue 根据名称查找枚举值
+func (e *Enum) LookupValue(name string) *EnumValue {
+	for _, v := range e.Values {
+		if v.GetName() == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// LookupValueByNumber 根据编号查找枚举值,存在别名时返回第一个使用这个编号的值
+func (e *Enum) LookupValueByNumber(number int32) *EnumValue {
+	for _, v := range e.Values {
+		if v.GetNumber() == number {
+			return v
+		}
 	}
-	return fmt.Sprintf("%s.%s", pkg, name)
+	return nil
 }
@@ -2,6 +2,7 @@ package gengo
 
 import (
 	"fmt"
+	"path"
 	"strings"
 
 	descriptor "github.com/yuansudong/gengo/descriptor"
@@ -21,6 +22,14 @@ func (p GoPackage) Standard() bool {
 	return !strings.Contains(p.Path, ".")
 }
 
+// _LocalName 返回Registry为这个包分配的全局包名,有别名时使用别名
+func (p GoPackage) _LocalName() string {
+	if p.Alias != "" {
+		return p.Alias
+	}
+	return p.Name
+}
+
 // String 返回一个包的完整路径
 func (p GoPackage) String() string {
 	if p.Alias == "" {
@@ -40,7 +49,17 @@ type File struct {
 	Enums []*Enum
 	// Services 定义在这个文件里的服务
 	Services []*Service
-	Imports  map[string]bool
+	// Imports 通过AddImportBy*记录的需要导入的包路径.
+	// 新代码建议为每个输出文件使用 Registry.NewGeneratedFile, 由它的导入管理器分配包名
+	Imports map[string]bool
+	// Generate 这个文件是否在FileToGenerate中,即是否需要为它生成代码
+	Generate bool
+	// _PathType 输出路径模式
+	_PathType PathType
+	// _Module 输出路径需要去掉的模块前缀
+	_Module string
+	// _Locations location path到SourceCodeInfo位置信息的索引
+	_Locations map[string]*descriptor.SourceCodeInfo_Location
 }
 
 // AddImportByPublic 用于增加
@@ -75,3 +94,65 @@ func (f *File) AddImportByEnum(e *Enum) {
 func (f *File) proto2() bool {
 	return f.Syntax == nil || f.GetSyntax() == "proto2"
 }
+
+// PathType 描述生成文件的输出路径模式
+type PathType int
+
+const (
+	// PathTypeImport 按照go包的导入路径输出,对应 paths=import
+	PathTypeImport PathType = iota
+	// PathTypeSourceRelative 输出到与proto文件相同的相对路径,对应 paths=source_relative
+	PathTypeSourceRelative
+)
+
+// ParsePathType 解析paths参数的值
+func ParsePathType(s string) (PathType, error) {
+	switch s {
+	case "import":
+		return PathTypeImport, nil
+	case "source_relative":
+		return PathTypeSourceRelative, nil
+	}
+	return 0, fmt.Errorf(`unknown path type %q: want "import" or "source_relative"`, s)
+}
+
+// String 返回paths参数对应的值
+func (t PathType) String() string {
+	switch t {
+	case PathTypeImport:
+		return "import"
+	case PathTypeSourceRelative:
+		return "source_relative"
+	}
+	return fmt.Sprintf("PathType(%d)", int(t))
+}
+
+// GeneratedFilenamePrefix 返回生成文件的路径前缀,不带扩展名.
+// 例如 foo/bar.proto 在 paths=source_relative 时返回 foo/bar.
+func (f *File) GeneratedFilenamePrefix() (string, error) {
+	name := f.GetName()
+	if ext := path.Ext(name); ext == ".proto" || ext == ".protodevel" {
+		name = name[:len(name)-len(ext)]
+	}
+	if f._PathType == PathTypeSourceRelative {
+		return name, nil
+	}
+	prefix := path.Join(f.GoPkg.Path, path.Base(name))
+	if f._Module == "" {
+		return prefix, nil
+	}
+	module := strings.TrimSuffix(f._Module, "/") + "/"
+	if !strings.HasPrefix(prefix, module) {
+		return "", fmt.Errorf("%s: generated file %s does not match prefix %q", f.GetName(), prefix, f._Module)
+	}
+	return strings.TrimPrefix(prefix, module), nil
+}
+
+// OutputPath 返回这个文件对应的生成文件名, suffix 为生成文件的后缀,例如 ".pb.gw.go"
+func (f *File) OutputPath(suffix string) (string, error) {
+	prefix, err := f.GeneratedFilenamePrefix()
+	if err != nil {
+		return "", err
+	}
+	return prefix + suffix, nil
+}
@@ -0,0 +1,92 @@
+package gengo
+
+import (
+	"strings"
+	"testing"
+
+	descriptor "github.com/yuansudong/gengo/descriptor"
+	plugin "github.com/yuansudong/gengo/plugin"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestOutputPath(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		param  string
+		file   string
+		goPkg  string
+		prefix string
+		err    string
+	}{
+		{name: "import", file: "foo/bar.proto", goPkg: "example.com/x/foo", prefix: "example.com/x/foo/bar"},
+		{name: "import with package name", file: "foo/bar.proto", goPkg: "example.com/x/foo;foopb", prefix: "example.com/x/foo/bar"},
+		{name: "protodevel", file: "foo/bar.protodevel", goPkg: "example.com/x/foo", prefix: "example.com/x/foo/bar"},
+		{name: "M overrides go_package", param: "Mfoo/bar.proto=example.com/m", file: "foo/bar.proto", goPkg: "example.com/x/foo", prefix: "example.com/m/bar"},
+		{name: "source_relative", param: "paths=source_relative", file: "foo/bar.proto", goPkg: "example.com/x/other", prefix: "foo/bar"},
+		{name: "module", param: "module=example.com/x", file: "foo/bar.proto", goPkg: "example.com/x/foo", prefix: "foo/bar"},
+		{name: "module with trailing slash", param: "module=example.com/x/", file: "foo/bar.proto", goPkg: "example.com/x/foo", prefix: "foo/bar"},
+		{name: "module with explicit paths=import", param: "paths=import,module=example.com/x", file: "foo/bar.proto", goPkg: "example.com/x/a/b", prefix: "a/b/bar"},
+		{
+			name: "outside module", param: "module=example.com/y", file: "foo/bar.proto", goPkg: "example.com/x/foo",
+			err: `foo/bar.proto: generated file example.com/x/foo/bar does not match prefix "example.com/y"`,
+		},
+		{
+			// 模块前缀必须在路径分隔处结束
+			name: "partial path element", param: "module=example.com/x/fo", file: "foo/bar.proto", goPkg: "example.com/x/foo",
+			err: "does not match prefix",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := ParseParams(tc.param)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := NewRegistry()
+			if err := r.ApplyParams(p); err != nil {
+				t.Fatalf("ApplyParams(%q) failed: %v", tc.param, err)
+			}
+			if err := r.Load(&plugin.CodeGeneratorRequest{
+				FileToGenerate: []string{tc.file},
+				ProtoFile: []*descriptor.FileDescriptorProto{{
+					Name:    proto.String(tc.file),
+					Package: proto.String("foo"),
+					Syntax:  proto.String("proto3"),
+					Options: &descriptor.FileOptions{GoPackage: proto.String(tc.goPkg)},
+				}},
+			}); err != nil {
+				t.Fatalf("Load() failed: %v", err)
+			}
+			f, err := r.LookupFile(tc.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			prefix, err := f.GeneratedFilenamePrefix()
+			out, outErr := f.OutputPath(".pb.gw.go")
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Errorf("GeneratedFilenamePrefix() = %q, %v; want error containing %q", prefix, err, tc.err)
+				}
+				if outErr == nil {
+					t.Errorf("OutputPath() = %q, want error", out)
+				}
+				return
+			}
+			if err != nil || prefix != tc.prefix {
+				t.Errorf("GeneratedFilenamePrefix() = %q, %v; want %q", prefix, err, tc.prefix)
+			}
+			if outErr != nil || out != tc.prefix+".pb.gw.go" {
+				t.Errorf("OutputPath() = %q, %v; want %q", out, outErr, tc.prefix+".pb.gw.go")
+			}
+		})
+	}
+}
+
+func TestApplyParamsRejectsModuleWithSourceRelative(t *testing.T) {
+	p, err := ParseParams("paths=source_relative,module=example.com/x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewRegistry().ApplyParams(p); err == nil || !strings.Contains(err.Error(), "cannot use module=example.com/x with paths=source_relative") {
+		t.Errorf("ApplyParams() = %v, want module/paths conflict", err)
+	}
+}
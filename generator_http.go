@@ -0,0 +1,182 @@
+package gengo
+
+import (
+	"fmt"
+
+	descriptor "github.com/yuansudong/gengo/descriptor"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// _HTTPRuleFieldNumber 是 google.api.http 在 MethodOptions 中的扩展编号
+const _HTTPRuleFieldNumber = 72295728
+
+// google.api.HttpRule 的字段编号
+const (
+	_HTTPRuleGet                = 2
+	_HTTPRulePut                = 3
+	_HTTPRulePost               = 4
+	_HTTPRuleDelete             = 5
+	_HTTPRulePatch              = 6
+	_HTTPRuleBody               = 7
+	_HTTPRuleCustom             = 8
+	_HTTPRuleAdditionalBindings = 11
+	_HTTPRuleResponseBody       = 12
+	_CustomHTTPPatternKind      = 1
+	_CustomHTTPPatternPath      = 2
+)
+
+// _HTTPRule 是从 google.api.http 中解码出来的规则.
+// 这里直接解码wire格式,插件不需要依赖googleapis生成的代码.
+type _HTTPRule struct {
+	HTTPMethod         string
+	Pattern            string
+	Body               string
+	ResponseBody       string
+	AdditionalBindings []*_HTTPRule
+}
+
+// Binding 描述一个RPC方法与HTTP请求之间的映射
+type Binding struct {
+	// Method 这个映射属于哪个RPC方法
+	Method *Method
+	// Index 在方法的所有映射中的下标,0 是主映射,其余来自 additional_bindings
+	Index int
+	// HTTPMethod HTTP的请求方法,例如 GET, POST
+	HTTPMethod string
+	// PathTmpl 解析后的HTTP路径模板
+	PathTmpl *PathTemplate
+	// PathParams 路径模板中的变量对应的请求字段
+	PathParams []Parameter
+	// Body 请求体对应的请求字段,没有请求体时为nil
+	Body *Body
+	// ResponseBody 响应体对应的响应字段,使用整个响应消息时为nil
+	ResponseBody *Body
+}
+
+// HasBody 判断这个映射是否有请求体
+func (b *Binding) HasBody() bool {
+	return b.Body != nil
+}
+
+// _ExtractHTTPRule 从方法的options中读取 google.api.http,
+// 扩展已经注册或者以未知字段的形式存在时都可以读取.
+func _ExtractHTTPRule(opts *descriptor.MethodOptions) (*_HTTPRule, error) {
+	if opts == nil {
+		return nil, nil
+	}
+	raw, err := proto.MarshalOptions{Deterministic: true}.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+	var rule *_HTTPRule
+	for len(raw) > 0 {
+		num, typ, n := protowire.ConsumeTag(raw)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		raw = raw[n:]
+		if num == _HTTPRuleFieldNumber && typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(raw)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			raw = raw[n:]
+			if rule, err = _ParseHTTPRule(v); err != nil {
+				return nil, fmt.Errorf("invalid google.api.http option: %v", err)
+			}
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, raw)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		raw = raw[n:]
+	}
+	return rule, nil
+}
+
+// _ParseHTTPRule 解码一个 google.api.HttpRule
+func _ParseHTTPRule(b []byte) (*_HTTPRule, error) {
+	rule := new(_HTTPRule)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		switch num {
+		case _HTTPRuleGet:
+			rule.HTTPMethod, rule.Pattern = "GET", string(v)
+		case _HTTPRulePut:
+			rule.HTTPMethod, rule.Pattern = "PUT", string(v)
+		case _HTTPRulePost:
+			rule.HTTPMethod, rule.Pattern = "POST", string(v)
+		case _HTTPRuleDelete:
+			rule.HTTPMethod, rule.Pattern = "DELETE", string(v)
+		case _HTTPRulePatch:
+			rule.HTTPMethod, rule.Pattern = "PATCH", string(v)
+		case _HTTPRuleCustom:
+			kind, pattern, err := _ParseCustomHTTPPattern(v)
+			if err != nil {
+				return nil, err
+			}
+			rule.HTTPMethod, rule.Pattern = kind, pattern
+		case _HTTPRuleBody:
+			rule.Body = string(v)
+		case _HTTPRuleResponseBody:
+			rule.ResponseBody = string(v)
+		case _HTTPRuleAdditionalBindings:
+			additional, err := _ParseHTTPRule(v)
+			if err != nil {
+				return nil, err
+			}
+			rule.AdditionalBindings = append(rule.AdditionalBindings, additional)
+		}
+	}
+	return rule, nil
+}
+
+// _ParseCustomHTTPPattern 解码一个 google.api.CustomHttpPattern
+func _ParseCustomHTTPPattern(b []byte) (kind, pattern string, err error) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return "", "", protowire.ParseError(n)
+		}
+		b = b[n:]
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return "", "", protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return "", "", protowire.ParseError(n)
+		}
+		b = b[n:]
+		switch num {
+		case _CustomHTTPPatternKind:
+			kind = string(v)
+		case _CustomHTTPPatternPath:
+			pattern = string(v)
+		}
+	}
+	return kind, pattern, nil
+}
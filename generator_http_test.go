@@ -0,0 +1,179 @@
+package gengo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	descriptor "github.com/yuansudong/gengo/descriptor"
+	plugin "github.com/yuansudong/gengo/plugin"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// _RuleField 是HttpRule中的一个字符串或消息字段
+type _RuleField struct {
+	num   protowire.Number
+	value []byte
+}
+
+// _EncodeRule 按顺序编码HttpRule的字段
+func _EncodeRule(fields ..._RuleField) []byte {
+	var b []byte
+	for _, f := range fields {
+		b = protowire.AppendTag(b, f.num, protowire.BytesType)
+		b = protowire.AppendBytes(b, f.value)
+	}
+	return b
+}
+
+// _Str 返回一个字符串字段
+func _Str(num protowire.Number, s string) _RuleField {
+	return _RuleField{num, []byte(s)}
+}
+
+// _RuleOptions 返回以未知字段形式携带raw的MethodOptions
+func _RuleOptions(raw []byte) *descriptor.MethodOptions {
+	opts := &descriptor.MethodOptions{Deprecated: proto.Bool(true)}
+	opts.ProtoReflect().SetUnknown(raw)
+	return opts
+}
+
+// _HTTPExtension 将HttpRule编码为 google.api.http 扩展
+func _HTTPExtension(rule []byte) []byte {
+	b := protowire.AppendTag(nil, _HTTPRuleFieldNumber, protowire.BytesType)
+	return protowire.AppendBytes(b, rule)
+}
+
+func TestExtractHTTPRule(t *testing.T) {
+	custom := _EncodeRule(_Str(_CustomHTTPPatternKind, "HEAD"), _Str(_CustomHTTPPatternPath, "/v1/{name}"))
+	for _, tc := range []struct {
+		name string
+		rule []byte
+		want *_HTTPRule
+	}{
+		{name: "get", rule: _EncodeRule(_Str(_HTTPRuleGet, "/v1/a")), want: &_HTTPRule{HTTPMethod: "GET", Pattern: "/v1/a"}},
+		{name: "put", rule: _EncodeRule(_Str(_HTTPRulePut, "/v1/a")), want: &_HTTPRule{HTTPMethod: "PUT", Pattern: "/v1/a"}},
+		{name: "post", rule: _EncodeRule(_Str(_HTTPRulePost, "/v1/a")), want: &_HTTPRule{HTTPMethod: "POST", Pattern: "/v1/a"}},
+		{name: "delete", rule: _EncodeRule(_Str(_HTTPRuleDelete, "/v1/a")), want: &_HTTPRule{HTTPMethod: "DELETE", Pattern: "/v1/a"}},
+		{name: "patch", rule: _EncodeRule(_Str(_HTTPRulePatch, "/v1/a")), want: &_HTTPRule{HTTPMethod: "PATCH", Pattern: "/v1/a"}},
+		{name: "custom", rule: _EncodeRule(_RuleField{_HTTPRuleCustom, custom}), want: &_HTTPRule{HTTPMethod: "HEAD", Pattern: "/v1/{name}"}},
+		{
+			name: "body and response_body",
+			rule: _EncodeRule(_Str(_HTTPRulePost, "/v1/a"), _Str(_HTTPRuleBody, "*"), _Str(_HTTPRuleResponseBody, "result")),
+			want: &_HTTPRule{HTTPMethod: "POST", Pattern: "/v1/a", Body: "*", ResponseBody: "result"},
+		},
+		{
+			name: "additional_bindings",
+			rule: _EncodeRule(
+				_Str(_HTTPRuleGet, "/v1/a"),
+				_RuleField{_HTTPRuleAdditionalBindings, _EncodeRule(_Str(_HTTPRulePost, "/v1/b"), _Str(_HTTPRuleBody, "*"))},
+				_RuleField{_HTTPRuleAdditionalBindings, _EncodeRule(_Str(_HTTPRuleDelete, "/v1/c"))},
+			),
+			want: &_HTTPRule{HTTPMethod: "GET", Pattern: "/v1/a", AdditionalBindings: []*_HTTPRule{
+				{HTTPMethod: "POST", Pattern: "/v1/b", Body: "*"},
+				{HTTPMethod: "DELETE", Pattern: "/v1/c"},
+			}},
+		},
+		{
+			// selector等非字符串字段以及未知字段会被跳过
+			name: "unknown fields",
+			rule: append(protowire.AppendVarint(protowire.AppendTag(nil, 99, protowire.VarintType), 1),
+				_EncodeRule(_Str(1, "pkg.Svc.Call"), _Str(_HTTPRuleGet, "/v1/a"))...),
+			want: &_HTTPRule{HTTPMethod: "GET", Pattern: "/v1/a"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := _ExtractHTTPRule(_RuleOptions(_HTTPExtension(tc.rule)))
+			if err != nil {
+				t.Fatalf("_ExtractHTTPRule() failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("_ExtractHTTPRule() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExtractHTTPRuleAbsent(t *testing.T) {
+	for _, opts := range []*descriptor.MethodOptions{
+		nil,
+		{},
+		_RuleOptions(protowire.AppendString(protowire.AppendTag(nil, 50000, protowire.BytesType), "other")),
+	} {
+		rule, err := _ExtractHTTPRule(opts)
+		if rule != nil || err != nil {
+			t.Errorf("_ExtractHTTPRule(%v) = %+v, %v; want nil, nil", opts, rule, err)
+		}
+	}
+}
+
+func TestExtractHTTPRuleMalformed(t *testing.T) {
+	get := _EncodeRule(_Str(_HTTPRuleGet, "/v1/a"))
+	for _, tc := range []struct {
+		name string
+		raw  []byte
+	}{
+		{name: "truncated extension", raw: _HTTPExtension(get)[:len(_HTTPExtension(get))-2]},
+		{name: "truncated tag", raw: []byte{0x80}},
+		{name: "truncated rule field", raw: _HTTPExtension(get[:len(get)-1])},
+		{name: "invalid rule tag", raw: _HTTPExtension([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01})},
+		{name: "truncated custom pattern", raw: _HTTPExtension(_EncodeRule(_RuleField{_HTTPRuleCustom, []byte{0x0a, 0x05, 'H'}}))},
+		{name: "truncated additional binding", raw: _HTTPExtension(_EncodeRule(_RuleField{_HTTPRuleAdditionalBindings, []byte{0x12, 0x05, '/'}}))},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if rule, err := _ExtractHTTPRule(_RuleOptions(tc.raw)); err == nil {
+				t.Errorf("_ExtractHTTPRule() = %+v, want error", rule)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsNestedAdditionalBindings(t *testing.T) {
+	nested := _EncodeRule(
+		_Str(_HTTPRuleGet, "/v1/{name}"),
+		_RuleField{_HTTPRuleAdditionalBindings, _EncodeRule(
+			_Str(_HTTPRuleGet, "/v2/{name}"),
+			_RuleField{_HTTPRuleAdditionalBindings, _EncodeRule(_Str(_HTTPRuleGet, "/v3/{name}"))},
+		)},
+	)
+	r := NewRegistry()
+	err := r.Load(&plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"svc.proto"},
+		ProtoFile:      []*descriptor.FileDescriptorProto{_ServiceFile("svc.proto", "svc", _RuleOptions(_HTTPExtension(nested)))},
+	})
+	if err == nil || !strings.Contains(err.Error(), "nested additional_bindings") {
+		t.Errorf("Load() = %v, want nested additional_bindings error", err)
+	}
+}
+
+func TestLoadBindingsFromUnknownExtension(t *testing.T) {
+	rule := _EncodeRule(
+		_Str(_HTTPRulePost, "/v1/{name}"),
+		_Str(_HTTPRuleBody, "*"),
+		_RuleField{_HTTPRuleAdditionalBindings, _EncodeRule(_Str(_HTTPRuleGet, "/v1/names/{name}"))},
+	)
+	r := NewRegistry()
+	if err := r.Load(&plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"svc.proto"},
+		ProtoFile:      []*descriptor.FileDescriptorProto{_ServiceFile("svc.proto", "svc", _RuleOptions(_HTTPExtension(rule)))},
+	}); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	meth, err := r.LookupMethod("svc.Svc.Call")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(meth.Bindings) != 2 {
+		t.Fatalf("Bindings = %d, want 2", len(meth.Bindings))
+	}
+	for i, want := range []struct {
+		method string
+		body   bool
+	}{{"POST", true}, {"GET", false}} {
+		b := meth.Bindings[i]
+		if b.Index != i || b.HTTPMethod != want.method || b.HasBody() != want.body || len(b.PathParams) != 1 {
+			t.Errorf("Bindings[%d] = %s index %d body %v params %d, want %s body %v", i, b.HTTPMethod, b.Index, b.HasBody(), len(b.PathParams), want.method, want.body)
+		}
+	}
+}
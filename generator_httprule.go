@@ -0,0 +1,412 @@
+package gengo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 编译后路径模板的操作码,与grpc-gateway的runtime.Pattern保持一致
+const (
+	// OpNop 空操作
+	OpNop = iota
+	// OpPush 匹配任意一个路径段,对应 *
+	OpPush
+	// OpLitPush 匹配一个字面量路径段,操作数是字面量在Pool中的下标
+	OpLitPush
+	// OpPushM 匹配剩余的所有路径段,对应 **
+	OpPushM
+	// OpConcatN 将栈顶的N个路径段拼接为一个
+	OpConcatN
+	// OpCapture 将栈顶的值赋给变量,操作数是字段路径在Pool中的下标
+	OpCapture
+)
+
+// _OpCodeVersion 编译后路径模板的版本
+const _OpCodeVersion = 1
+
+// SegmentKind 描述路径段的类型
+type SegmentKind int
+
+const (
+	// SegmentLiteral 字面量路径段
+	SegmentLiteral SegmentKind = iota
+	// SegmentWildcard 单个路径段的通配符 *
+	SegmentWildcard
+	// SegmentDeepWildcard 多个路径段的通配符 **
+	SegmentDeepWildcard
+	// SegmentVariable 变量 {field.path=...}
+	SegmentVariable
+)
+
+// PathSegment 描述路径模板中的一段
+type PathSegment struct {
+	// Kind 路径段的类型
+	Kind SegmentKind
+	// Literal 字面量路径段的值
+	Literal string
+	// Variable Kind为SegmentVariable时的变量
+	Variable *PathVariable
+}
+
+// PathVariable 描述路径模板中的一个变量
+type PathVariable struct {
+	// FieldPath 变量对应的请求字段路径,例如 book.name
+	FieldPath string
+	// Segments 变量匹配的路径段,{name} 等价于 {name=*}
+	Segments []PathSegment
+}
+
+// PathTemplate 是解析后的HTTP路径模板
+type PathTemplate struct {
+	// Template 原始的模板字符串
+	Template string
+	// Segments 模板的路径段
+	Segments []PathSegment
+	// Verb 自定义动词,例如 /v1/{name}:cancel 中的 cancel
+	Verb string
+}
+
+// CompiledTemplate 是编译成操作码形式的路径模板,
+// 生成的路由代码可以直接使用,不需要在运行时重新解析模板
+type CompiledTemplate struct {
+	// Version 操作码的版本
+	Version int
+	// OpCodes 操作码和操作数交替排列
+	OpCodes []int
+	// Pool 操作数引用的字符串
+	Pool []string
+	// Verb 自定义动词
+	Verb string
+	// Fields 模板中所有变量的字段路径
+	Fields []string
+	// Template 原始的模板字符串
+	Template string
+}
+
+// ParsePathTemplate 解析一个 google.api.http 的路径模板. 语法如下:
+//
+//	Template = "/" Segments [ Verb ] ;
+//	Segments = Segment { "/" Segment } ;
+//	Segment  = "*" | "**" | LITERAL | Variable ;
+//	Variable = "{" FieldPath [ "=" Segments ] "}" ;
+//	FieldPath = IDENT { "." IDENT } ;
+//	Verb     = ":" LITERAL ;
+func ParsePathTemplate(tmpl string) (*PathTemplate, error) {
+	if !strings.HasPrefix(tmpl, "/") {
+		return nil, fmt.Errorf("path template %q must start with /", tmpl)
+	}
+	body, verb, err := _SplitVerb(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path template %q: %v", tmpl, err)
+	}
+	if body == "/" && verb == "" {
+		return &PathTemplate{Template: tmpl}, nil
+	}
+	p := &_TemplateParser{input: body[1:]}
+	segs, err := p.segments(false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path template %q: %v", tmpl, err)
+	}
+	if p.input != "" {
+		return nil, fmt.Errorf("invalid path template %q: unexpected %q", tmpl, p.input)
+	}
+	t := &PathTemplate{Template: tmpl, Segments: segs, Verb: verb}
+	if err := t._Validate(); err != nil {
+		return nil, fmt.Errorf("invalid path template %q: %v", tmpl, err)
+	}
+	return t, nil
+}
+
+// _SplitVerb 拆分出模板末尾的自定义动词,动词只能出现在最后一个路径段且不在变量内.
+// : 之后的动词不能为空.
+func _SplitVerb(tmpl string) (string, string, error) {
+	depth, idx := 0, -1
+	for i, c := range tmpl {
+		switch c {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '/':
+			if depth == 0 {
+				idx = -1
+			}
+		case ':':
+			if depth == 0 {
+				idx = i
+			}
+		}
+	}
+	if idx < 0 {
+		return tmpl, "", nil
+	}
+	if idx == len(tmpl)-1 {
+		return "", "", fmt.Errorf("empty verb after :")
+	}
+	return tmpl[:idx], tmpl[idx+1:], nil
+}
+
+// _Validate 检查模板的语义: ** 只能是最后一段,变量不能重复
+func (t *PathTemplate) _Validate() error {
+	var all []PathSegment
+	seen := make(map[string]bool)
+	for _, seg := range t.Segments {
+		if seg.Kind != SegmentVariable {
+			all = append(all, seg)
+			continue
+		}
+		if seen[seg.Variable.FieldPath] {
+			return fmt.Errorf("duplicate variable %s", seg.Variable.FieldPath)
+		}
+		seen[seg.Variable.FieldPath] = true
+		all = append(all, seg.Variable.Segments...)
+	}
+	for i, seg := range all {
+		if seg.Kind == SegmentDeepWildcard && i != len(all)-1 {
+			return fmt.Errorf("** must be the last segment")
+		}
+	}
+	if strings.ContainsAny(t.Verb, "/{}*=") {
+		return fmt.Errorf("invalid verb %q", t.Verb)
+	}
+	return nil
+}
+
+// Fields 返回模板中所有变量的字段路径
+func (t *PathTemplate) Fields() []string {
+	var fields []string
+	for _, seg := range t.Segments {
+		if seg.Kind == SegmentVariable {
+			fields = append(fields, seg.Variable.FieldPath)
+		}
+	}
+	return fields
+}
+
+// Compile 将模板编译为操作码形式
+func (t *PathTemplate) Compile() CompiledTemplate {
+	c := CompiledTemplate{
+		Version:  _OpCodeVersion,
+		Verb:     t.Verb,
+		Template: t.Template,
+	}
+	pool := make(map[string]int)
+	intern := func(s string) int {
+		if idx, ok := pool[s]; ok {
+			return idx
+		}
+		idx := len(c.Pool)
+		pool[s] = idx
+		c.Pool = append(c.Pool, s)
+		return idx
+	}
+	var compile func(seg PathSegment)
+	compile = func(seg PathSegment) {
+		switch seg.Kind {
+		case SegmentLiteral:
+			c.OpCodes = append(c.OpCodes, OpLitPush, intern(seg.Literal))
+		case SegmentWildcard:
+			c.OpCodes = append(c.OpCodes, OpPush, 0)
+		case SegmentDeepWildcard:
+			c.OpCodes = append(c.OpCodes, OpPushM, 0)
+		case SegmentVariable:
+			for _, s := range seg.Variable.Segments {
+				compile(s)
+			}
+			c.OpCodes = append(c.OpCodes, OpConcatN, len(seg.Variable.Segments))
+			c.OpCodes = append(c.OpCodes, OpCapture, intern(seg.Variable.FieldPath))
+			c.Fields = append(c.Fields, seg.Variable.FieldPath)
+		}
+	}
+	for _, seg := range t.Segments {
+		compile(seg)
+	}
+	return c
+}
+
+// RoutePattern 将模板渲染为gorilla/mux风格的路由字符串.
+// 变量渲染为 {field.path}, 匹配多个路径段的变量会带上正则,
+// 例如 /v1/{name=shelves/*/books/*} 渲染为 /v1/{name:shelves/[^/]+/books/[^/]+}.
+// 变量之外的通配符按出现顺序命名为 _0, _1 ...
+func (t *PathTemplate) RoutePattern() string {
+	var parts []string
+	anon := 0
+	for _, seg := range t.Segments {
+		switch seg.Kind {
+		case SegmentLiteral:
+			parts = append(parts, seg.Literal)
+		case SegmentWildcard, SegmentDeepWildcard:
+			parts = append(parts, fmt.Sprintf("{_%d:%s}", anon, _SegmentsRegexp([]PathSegment{seg})))
+			anon++
+		case SegmentVariable:
+			v := seg.Variable
+			if len(v.Segments) == 1 && v.Segments[0].Kind == SegmentWildcard {
+				parts = append(parts, "{"+v.FieldPath+"}")
+				continue
+			}
+			parts = append(parts, "{"+v.FieldPath+":"+_SegmentsRegexp(v.Segments)+"}")
+		}
+	}
+	pattern := "/" + strings.Join(parts, "/")
+	if t.Verb != "" {
+		pattern += ":" + t.Verb
+	}
+	return pattern
+}
+
+// _SegmentsRegexp 将路径段渲染为正则表达式
+func _SegmentsRegexp(segs []PathSegment) string {
+	parts := make([]string, 0, len(segs))
+	for _, seg := range segs {
+		switch seg.Kind {
+		case SegmentWildcard:
+			parts = append(parts, "[^/]+")
+		case SegmentDeepWildcard:
+			parts = append(parts, ".*")
+		default:
+			parts = append(parts, _QuoteRegexp(seg.Literal))
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// _QuoteRegexp 转义字面量中的正则特殊字符
+func _QuoteRegexp(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(`\.+*?()|[]{}^$`, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// OpCodesExpr 返回操作码的Go字面量,例如 []int{2, 0, 1, 0}
+func (c CompiledTemplate) OpCodesExpr() string {
+	items := make([]string, len(c.OpCodes))
+	for i, op := range c.OpCodes {
+		items[i] = strconv.Itoa(op)
+	}
+	return "[]int{" + strings.Join(items, ", ") + "}"
+}
+
+// PoolExpr 返回字符串池的Go字面量,例如 []string{"v1", "name"}
+func (c CompiledTemplate) PoolExpr() string {
+	items := make([]string, len(c.Pool))
+	for i, s := range c.Pool {
+		items[i] = strconv.Quote(s)
+	}
+	return "[]string{" + strings.Join(items, ", ") + "}"
+}
+
+// _TemplateParser 路径模板的递归下降解析器
+type _TemplateParser struct {
+	input string
+}
+
+// segments 解析以 / 分隔的路径段, inVar 表示是否在变量内部
+func (p *_TemplateParser) segments(inVar bool) ([]PathSegment, error) {
+	var segs []PathSegment
+	for {
+		seg, err := p.segment(inVar)
+		if err != nil {
+			return nil, err
+		}
+		segs = append(segs, seg)
+		if !strings.HasPrefix(p.input, "/") {
+			return segs, nil
+		}
+		p.input = p.input[1:]
+	}
+}
+
+// segment 解析一个路径段
+func (p *_TemplateParser) segment(inVar bool) (PathSegment, error) {
+	switch {
+	case strings.HasPrefix(p.input, "**"):
+		p.input = p.input[2:]
+		return PathSegment{Kind: SegmentDeepWildcard}, nil
+	case strings.HasPrefix(p.input, "*"):
+		p.input = p.input[1:]
+		return PathSegment{Kind: SegmentWildcard}, nil
+	case strings.HasPrefix(p.input, "{"):
+		if inVar {
+			return PathSegment{}, fmt.Errorf("nested variables are not allowed")
+		}
+		v, err := p.variable()
+		if err != nil {
+			return PathSegment{}, err
+		}
+		return PathSegment{Kind: SegmentVariable, Variable: v}, nil
+	}
+	lit := p.literal()
+	if lit == "" {
+		if p.input == "" {
+			return PathSegment{}, fmt.Errorf("unexpected end of template")
+		}
+		return PathSegment{}, fmt.Errorf("unexpected %q", p.input)
+	}
+	return PathSegment{Kind: SegmentLiteral, Literal: lit}, nil
+}
+
+// variable 解析一个变量 {field.path=segments}
+func (p *_TemplateParser) variable() (*PathVariable, error) {
+	p.input = p.input[1:]
+	end := strings.IndexAny(p.input, "=}")
+	if end < 0 {
+		return nil, fmt.Errorf("unterminated variable")
+	}
+	fieldPath := p.input[:end]
+	if err := _ValidateFieldPath(fieldPath); err != nil {
+		return nil, err
+	}
+	p.input = p.input[end:]
+	v := &PathVariable{FieldPath: fieldPath}
+	if strings.HasPrefix(p.input, "=") {
+		p.input = p.input[1:]
+		segs, err := p.segments(true)
+		if err != nil {
+			return nil, err
+		}
+		v.Segments = segs
+	} else {
+		v.Segments = []PathSegment{{Kind: SegmentWildcard}}
+	}
+	if !strings.HasPrefix(p.input, "}") {
+		return nil, fmt.Errorf("unterminated variable %s", fieldPath)
+	}
+	p.input = p.input[1:]
+	return v, nil
+}
+
+// literal 读取一个字面量路径段
+func (p *_TemplateParser) literal() string {
+	end := strings.IndexAny(p.input, "/{}=*")
+	if end < 0 {
+		end = len(p.input)
+	}
+	lit := p.input[:end]
+	p.input = p.input[end:]
+	return lit
+}
+
+// _ValidateFieldPath 检查变量的字段路径是否由合法的标识符组成
+func _ValidateFieldPath(fieldPath string) error {
+	if fieldPath == "" {
+		return fmt.Errorf("empty variable name")
+	}
+	for _, ident := range strings.Split(fieldPath, ".") {
+		if ident == "" {
+			return fmt.Errorf("invalid field path %q", fieldPath)
+		}
+		for i, c := range ident {
+			isLetter := c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+			if !isLetter && (i == 0 || c < '0' || c > '9') {
+				return fmt.Errorf("invalid field path %q", fieldPath)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package gengo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPathTemplateCompile(t *testing.T) {
+	// 期望的操作码与grpc-gateway的httprule.Compile的输出一致
+	for _, tc := range []struct {
+		tmpl   string
+		ops    []int
+		pool   []string
+		fields []string
+		verb   string
+	}{
+		{tmpl: "/"},
+		{
+			tmpl: "/v1",
+			ops:  []int{OpLitPush, 0},
+			pool: []string{"v1"},
+		},
+		{
+			tmpl: "/v1/*",
+			ops:  []int{OpLitPush, 0, OpPush, 0},
+			pool: []string{"v1"},
+		},
+		{
+			tmpl: "/v1/**",
+			ops:  []int{OpLitPush, 0, OpPushM, 0},
+			pool: []string{"v1"},
+		},
+		{
+			tmpl:   "/v1/{name}",
+			ops:    []int{OpLitPush, 0, OpPush, 0, OpConcatN, 1, OpCapture, 1},
+			pool:   []string{"v1", "name"},
+			fields: []string{"name"},
+		},
+		{
+			tmpl:   "/v1/{name.nested}",
+			ops:    []int{OpLitPush, 0, OpPush, 0, OpConcatN, 1, OpCapture, 1},
+			pool:   []string{"v1", "name.nested"},
+			fields: []string{"name.nested"},
+		},
+		{
+			tmpl: "/v1/{name=shelves/*/books/*}",
+			ops: []int{
+				OpLitPush, 0,
+				OpLitPush, 1, OpPush, 0, OpLitPush, 2, OpPush, 0,
+				OpConcatN, 4, OpCapture, 3,
+			},
+			pool:   []string{"v1", "shelves", "books", "name"},
+			fields: []string{"name"},
+		},
+		{
+			tmpl:   "/v1/{name=**}:cancel",
+			ops:    []int{OpLitPush, 0, OpPushM, 0, OpConcatN, 1, OpCapture, 1},
+			pool:   []string{"v1", "name"},
+			fields: []string{"name"},
+			verb:   "cancel",
+		},
+		{
+			tmpl: "/v1/a/a/{a.b}/{c=a/*}",
+			ops: []int{
+				OpLitPush, 0, OpLitPush, 1, OpLitPush, 1,
+				OpPush, 0, OpConcatN, 1, OpCapture, 2,
+				OpLitPush, 1, OpPush, 0, OpConcatN, 2, OpCapture, 3,
+			},
+			pool:   []string{"v1", "a", "a.b", "c"},
+			fields: []string{"a.b", "c"},
+		},
+	} {
+		t.Run(tc.tmpl, func(t *testing.T) {
+			tmpl, err := ParsePathTemplate(tc.tmpl)
+			if err != nil {
+				t.Fatalf("ParsePathTemplate() failed: %v", err)
+			}
+			c := tmpl.Compile()
+			if c.Version != 1 || c.Template != tc.tmpl || c.Verb != tc.verb {
+				t.Errorf("Compile() version=%d template=%q verb=%q", c.Version, c.Template, c.Verb)
+			}
+			if !reflect.DeepEqual(c.OpCodes, tc.ops) {
+				t.Errorf("Compile() ops = %v, want %v", c.OpCodes, tc.ops)
+			}
+			if !reflect.DeepEqual(c.Pool, tc.pool) {
+				t.Errorf("Compile() pool = %q, want %q", c.Pool, tc.pool)
+			}
+			if !reflect.DeepEqual(c.Fields, tc.fields) {
+				t.Errorf("Compile() fields = %q, want %q", c.Fields, tc.fields)
+			}
+			if got := tmpl.Fields(); !reflect.DeepEqual(got, tc.fields) {
+				t.Errorf("Fields() = %q, want %q", got, tc.fields)
+			}
+		})
+	}
+}
+
+func TestParsePathTemplateErrors(t *testing.T) {
+	for _, tmpl := range []string{
+		"",
+		"v1/{name}",
+		"/v1/{name}:",
+		"/v1:",
+		"/v1//books",
+		"/v1/",
+		"/v1/**/books",
+		"/v1/{name=**}/books",
+		"/v1/{name}/{name}",
+		"/v1/{name={id}}",
+		"/v1/{name",
+		"/v1/{name=shelves/*",
+		"/v1/{}",
+		"/v1/{1name}",
+		"/v1/{a..b}",
+		"/v1/{name}:x/y",
+		"/v1/name}",
+	} {
+		if got, err := ParsePathTemplate(tmpl); err == nil {
+			t.Errorf("ParsePathTemplate(%q) = %+v, want error", tmpl, got)
+		}
+	}
+}
+
+func TestPathTemplateRoutePattern(t *testing.T) {
+	for _, tc := range []struct {
+		tmpl string
+		want string
+	}{
+		{tmpl: "/", want: "/"},
+		{tmpl: "/v1/{name}", want: "/v1/{name}"},
+		{tmpl: "/v1/{name}:cancel", want: "/v1/{name}:cancel"},
+		{tmpl: "/v1/{name=shelves/*/books/*}", want: "/v1/{name:shelves/[^/]+/books/[^/]+}"},
+		{tmpl: "/v1/*/x/**", want: "/v1/{_0:[^/]+}/x/{_1:.*}"},
+		{tmpl: "/v1.a/{file=**}", want: "/v1.a/{file:.*}"},
+	} {
+		tmpl, err := ParsePathTemplate(tc.tmpl)
+		if err != nil {
+			t.Fatalf("ParsePathTemplate(%q) failed: %v", tc.tmpl, err)
+		}
+		if got := tmpl.RoutePattern(); got != tc.want {
+			t.Errorf("RoutePattern(%q) = %q, want %q", tc.tmpl, got, tc.want)
+		}
+	}
+}
+
+func TestCompiledTemplateExprs(t *testing.T) {
+	tmpl, err := ParsePathTemplate(`/v1/{name}`)
+	if err != nil {
+		t.Fatalf("ParsePathTemplate() failed: %v", err)
+	}
+	c := tmpl.Compile()
+	if got, want := c.OpCodesExpr(), "[]int{2, 0, 1, 0, 4, 1, 5, 1}"; got != want {
+		t.Errorf("OpCodesExpr() = %s, want %s", got, want)
+	}
+	if got, want := c.PoolExpr(), `[]string{"v1", "name"}`; got != want {
+		t.Errorf("PoolExpr() = %s, want %s", got, want)
+	}
+}
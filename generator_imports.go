@@ -0,0 +1,196 @@
+package gengo
+
+import (
+	"fmt"
+	"go/token"
+	"path"
+	"sort"
+	"strings"
+)
+
+// _PredeclaredIdents 是Go的预声明标识符,包名不应该遮蔽它们
+var _PredeclaredIdents = map[string]bool{
+	"bool": true, "byte": true, "complex64": true, "complex128": true,
+	"error": true, "float32": true, "float64": true, "int": true,
+	"int8": true, "int16": true, "int32": true, "int64": true,
+	"rune": true, "string": true, "uint": true, "uint8": true,
+	"uint16": true, "uint32": true, "uint64": true, "uintptr": true,
+	"true": true, "false": true, "iota": true, "nil": true,
+	"append": true, "cap": true, "close": true, "complex": true,
+	"copy": true, "delete": true, "imag": true, "len": true,
+	"make": true, "new": true, "panic": true, "print": true,
+	"println": true, "real": true, "recover": true,
+}
+
+// ImportManager 管理一个生成文件的导入,为每个引用到的包分配稳定且不冲突的本地包名.
+// 本地包名会避开Go关键字,预声明标识符以及通过Reserve保留的生成标识符.
+type ImportManager struct {
+	// _Self 生成文件所在的包,引用这个包时不需要导入
+	_Self GoPackage
+	// _Names 导入路径到本地包名的映射
+	_Names map[string]string
+	// _Used 已经被占用的本地名字
+	_Used map[string]bool
+	// _Pkgs 已经导入的包,按导入路径索引
+	_Pkgs map[string]GoPackage
+	// _Assigned 通过Assign预先分配的本地包名,按导入路径索引
+	_Assigned map[string]string
+}
+
+// NewImportManager 为属于self包的生成文件创建一个导入管理器
+func NewImportManager(self GoPackage) *ImportManager {
+	return &ImportManager{
+		_Self:     self,
+		_Names:    make(map[string]string),
+		_Used:     make(map[string]bool),
+		_Pkgs:     make(map[string]GoPackage),
+		_Assigned: make(map[string]string),
+	}
+}
+
+// Assign 预先为导入路径pkgPath分配本地包名name,引用这个包时总是使用name,
+// 其他包不会再分配到name. 同一个路径只有第一次分配生效. Registry.NewGeneratedFile 用它使导入与 Message.GoType 一致.
+func (im *ImportManager) Assign(pkgPath, name string) {
+	if pkgPath == im._Self.Path {
+		return
+	}
+	if _, ok := im._Assigned[pkgPath]; ok {
+		return
+	}
+	im._Used[name] = true
+	im._Assigned[pkgPath] = name
+}
+
+// Reserve 保留生成文件中的顶层标识符,导入的包名不会与它们冲突.
+// 需要在引用相应的包之前调用.
+func (im *ImportManager) Reserve(names ...string) {
+	for _, name := range names {
+		im._Used[name] = true
+	}
+}
+
+// Use 记录对pkg的引用并返回它在生成文件中的本地包名,
+// pkg就是生成文件所在的包时返回空字符串.
+func (im *ImportManager) Use(pkg GoPackage) string {
+	if pkg.Path == im._Self.Path {
+		return ""
+	}
+	if name, ok := im._Names[pkg.Path]; ok {
+		return name
+	}
+	if name, ok := im._Assigned[pkg.Path]; ok {
+		im._Names[pkg.Path] = name
+		pkg.Alias = name
+		im._Pkgs[pkg.Path] = pkg
+		return name
+	}
+	base := pkg.Name
+	if base == "" {
+		base = PackageNameFromPath(pkg.Path)
+	}
+	base = _SanitizeIdent(base)
+	name := base
+	for i := 1; im._Used[name] || token.Lookup(name).IsKeyword() || _PredeclaredIdents[name]; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	im._Used[name] = true
+	im._Names[pkg.Path] = name
+	pkg.Alias = name
+	im._Pkgs[pkg.Path] = pkg
+	return name
+}
+
+// Qualify 返回在生成文件中引用pkg中的标识符ident的表达式
+func (im *ImportManager) Qualify(pkg GoPackage, ident string) string {
+	if name := im.Use(pkg); name != "" {
+		return name + "." + ident
+	}
+	return ident
+}
+
+// Imports 返回按导入路径排序的所有导入
+func (im *ImportManager) Imports() []GoPackage {
+	pkgs := make([]GoPackage, 0, len(im._Pkgs))
+	for _, pkg := range im._Pkgs {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Slice(pkgs, func(i, j int) bool {
+		return pkgs[i].Path < pkgs[j].Path
+	})
+	return pkgs
+}
+
+// Render 渲染导入块,标准库与第三方包分为两组,组内按导入路径排序.
+// 本地包名,包名与导入路径的最后一段都相同时省略别名.
+func (im *ImportManager) Render() string {
+	pkgs := im.Imports()
+	if len(pkgs) == 0 {
+		return ""
+	}
+	var std, others []string
+	for _, pkg := range pkgs {
+		line := fmt.Sprintf("%s %q", pkg.Alias, pkg.Path)
+		if pkg.Alias == pkg.Name && pkg.Name == path.Base(pkg.Path) {
+			line = fmt.Sprintf("%q", pkg.Path)
+		}
+		if pkg.Standard() {
+			std = append(std, "\t"+line)
+		} else {
+			others = append(others, "\t"+line)
+		}
+	}
+	var groups []string
+	if len(std) > 0 {
+		groups = append(groups, strings.Join(std, "\n"))
+	}
+	if len(others) > 0 {
+		groups = append(groups, strings.Join(others, "\n"))
+	}
+	return "import (\n" + strings.Join(groups, "\n\n") + "\n)\n"
+}
+
+// PackageNameFromPath 按照Go的惯例由导入路径推断包名.
+// 路径以主版本号结尾时使用上一段,例如 github.com/foo/bar/v2 的包名是 bar,
+// gopkg.in/yaml.v2 的包名是 yaml.
+func PackageNameFromPath(importPath string) string {
+	base := path.Base(importPath)
+	if _IsMajorVersion(base) {
+		if dir := path.Dir(importPath); dir != "." && dir != "/" {
+			base = path.Base(dir)
+		}
+	}
+	if i := strings.LastIndex(base, "."); i > 0 && _IsMajorVersion(base[i+1:]) {
+		base = base[:i]
+	}
+	return base
+}
+
+// _IsMajorVersion 判断s是否是 v2, v3 这样的主版本号
+func _IsMajorVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	for _, c := range s[1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s[1] != '0'
+}
+
+// _SanitizeIdent 将字符串转换为合法的Go标识符
+func _SanitizeIdent(s string) string {
+	var b strings.Builder
+	for i, c := range s {
+		isLetter := c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+		if isLetter || (i > 0 && '0' <= c && c <= '9') {
+			b.WriteRune(c)
+			continue
+		}
+		b.WriteByte('_')
+	}
+	if b.Len() == 0 {
+		return "_pkg"
+	}
+	return b.String()
+}
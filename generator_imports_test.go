@@ -0,0 +1,178 @@
+package gengo
+
+import (
+	"reflect"
+	"testing"
+
+	descriptor "github.com/yuansudong/gengo/descriptor"
+	plugin "github.com/yuansudong/gengo/plugin"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestPackageNameFromPath(t *testing.T) {
+	for _, tc := range []struct {
+		path string
+		want string
+	}{
+		{path: "fmt", want: "fmt"},
+		{path: "net/http", want: "http"},
+		{path: "github.com/foo/bar", want: "bar"},
+		{path: "github.com/foo/bar/v2", want: "bar"},
+		{path: "github.com/foo/bar/v10", want: "bar"},
+		{path: "gopkg.in/yaml.v2", want: "yaml"},
+		{path: "github.com/foo/v0", want: "v0"},
+		{path: "github.com/foo/version", want: "version"},
+		{path: "v2", want: "v2"},
+	} {
+		if got := PackageNameFromPath(tc.path); got != tc.want {
+			t.Errorf("PackageNameFromPath(%q) = %q, want %q", tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestImportManagerUse(t *testing.T) {
+	im := NewImportManager(GoPackage{Path: "example.com/self", Name: "self"})
+	im.Reserve("runtime")
+	for _, tc := range []struct {
+		pkg  GoPackage
+		want string
+	}{
+		{pkg: GoPackage{Path: "example.com/self", Name: "self"}, want: ""},
+		{pkg: GoPackage{Path: "fmt", Name: "fmt"}, want: "fmt"},
+		{pkg: GoPackage{Path: "fmt", Name: "fmt"}, want: "fmt"},
+		{pkg: GoPackage{Path: "github.com/a/runtime", Name: "runtime"}, want: "runtime1"},
+		{pkg: GoPackage{Path: "github.com/b/runtime", Name: "runtime"}, want: "runtime2"},
+		{pkg: GoPackage{Path: "example.com/string", Name: "string"}, want: "string1"},
+		{pkg: GoPackage{Path: "example.com/type", Name: "type"}, want: "type1"},
+		{pkg: GoPackage{Path: "example.com/foo/v2"}, want: "foo"},
+		{pkg: GoPackage{Path: "example.com/foo-bar", Name: "foo-bar"}, want: "foo_bar"},
+	} {
+		if got := im.Use(tc.pkg); got != tc.want {
+			t.Errorf("Use(%s) = %q, want %q", tc.pkg.Path, got, tc.want)
+		}
+	}
+}
+
+func TestImportManagerRender(t *testing.T) {
+	im := NewImportManager(GoPackage{Path: "example.com/self", Name: "self"})
+	im.Use(GoPackage{Path: "strings", Name: "strings"})
+	im.Use(GoPackage{Path: "github.com/foo/bar/v2", Name: "bar"})
+	im.Use(GoPackage{Path: "fmt", Name: "fmt"})
+	im.Use(GoPackage{Path: "example.com/proto", Name: "proto"})
+	want := `import (
+	"fmt"
+	"strings"
+
+	"example.com/proto"
+	bar "github.com/foo/bar/v2"
+)
+`
+	if got := im.Render(); got != want {
+		t.Errorf("Render() = %s, want %s", got, want)
+	}
+	if got := NewImportManager(GoPackage{}).Render(); got != "" {
+		t.Errorf("Render() without imports = %q, want empty", got)
+	}
+}
+
+func TestAddImportBy(t *testing.T) {
+	self := &File{GoPkg: GoPackage{Path: "example.com/self", Name: "self"}}
+	dep := &File{GoPkg: GoPackage{Path: "example.com/dep", Name: "dep"}}
+	self.AddImportByPublic("github.com/foo/bar/v2")
+	self.AddImportByMessage(&Message{File: dep})
+	self.AddImportByEnum(&Enum{File: self})
+	want := map[string]bool{"github.com/foo/bar/v2": true, "example.com/dep": true}
+	if !reflect.DeepEqual(self.Imports, want) {
+		t.Errorf("Imports = %v, want %v", self.Imports, want)
+	}
+}
+
+func TestGoTypeInMatchesImports(t *testing.T) {
+	dep := &File{
+		FileDescriptorProto: &descriptor.FileDescriptorProto{Name: proto.String("dep.proto")},
+		// Alias 是Registry分配的全局别名,生成文件中不应该使用它
+		GoPkg: GoPackage{Path: "example.com/dep/v2", Name: "dep", Alias: "dep_1"},
+	}
+	msg := &Message{File: dep, DescriptorProto: &descriptor.DescriptorProto{Name: proto.String("Msg")}}
+	enum := &Enum{File: dep, EnumDescriptorProto: &descriptor.EnumDescriptorProto{Name: proto.String("Kind")}}
+
+	g := NewGeneratedFile("out.go", GoPackage{Path: "example.com/out", Name: "out"})
+	g.Imports().Reserve("dep")
+	if got, want := g.MessageType(msg), "dep1.Msg"; got != want {
+		t.Errorf("MessageType() = %q, want %q", got, want)
+	}
+	if got, want := g.EnumType(enum), "dep1.Kind"; got != want {
+		t.Errorf("EnumType() = %q, want %q", got, want)
+	}
+	want := "import (\n\tdep1 \"example.com/dep/v2\"\n)\n"
+	if got := g.Imports().Render(); got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+}
+
+// _ImportFile 返回一个go_package为pkgPath,包含消息Msg和枚举Kind的文件
+func _ImportFile(name, pkg, pkgPath string) *descriptor.FileDescriptorProto {
+	return &descriptor.FileDescriptorProto{
+		Name:        proto.String(name),
+		Package:     proto.String(pkg),
+		Syntax:      proto.String("proto3"),
+		Options:     &descriptor.FileOptions{GoPackage: proto.String(pkgPath)},
+		MessageType: []*descriptor.DescriptorProto{{Name: proto.String("Msg")}},
+		EnumType: []*descriptor.EnumDescriptorProto{{
+			Name:  proto.String("Kind"),
+			Value: []*descriptor.EnumValueDescriptorProto{{Name: proto.String("KIND_UNSPECIFIED"), Number: proto.Int32(0)}},
+		}},
+	}
+}
+
+func TestRegistryGeneratedFileMatchesGoType(t *testing.T) {
+	r := NewRegistry()
+	r.SetMultiPackage(true)
+	if err := r.Load(&plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"out.proto"},
+		ProtoFile: []*descriptor.FileDescriptorProto{
+			_ImportFile("a/dep.proto", "a", "example.com/a/dep"),
+			// 与a/dep.proto的包名相同,Registry为它分配别名dep_0
+			_ImportFile("b/dep.proto", "b", "example.com/b/dep;dep"),
+			_ImportFile("out.proto", "out", "example.com/out"),
+		},
+	}); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	out, err := r.LookupFile("out.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := r.NewGeneratedFile("out.pb.go", out.GoPkg)
+	// 先导入一个同名的非proto包,它不能占用proto包的包名
+	if got := g.Import(GoPackage{Path: "example.com/other/dep", Name: "dep"}); got != "dep1" {
+		t.Errorf("Import(example.com/other/dep) = %q, want dep1", got)
+	}
+	for _, fqmn := range []string{".a.Msg", ".b.Msg", ".out.Msg"} {
+		m, err := r.LookupMsg("", fqmn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := g.MessageType(m), m.GoType(out.GoPkg.Path); got != want {
+			t.Errorf("%s: MessageType() = %q, GoType() = %q", fqmn, got, want)
+		}
+	}
+	for _, fqen := range []string{".a.Kind", ".b.Kind"} {
+		e, err := r.LookupEnum("", fqen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := g.EnumType(e), e.GoType(out.GoPkg.Path); got != want {
+			t.Errorf("%s: EnumType() = %q, GoType() = %q", fqen, got, want)
+		}
+	}
+	want := "import (\n\t\"example.com/a/dep\"\n\tdep_0 \"example.com/b/dep\"\n\tdep1 \"example.com/other/dep\"\n)\n"
+	if got := g.Imports().Render(); got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+
+	// 同一个proto文件的另一个输出文件有独立的导入
+	if got := r.NewGeneratedFile("out.pb.gw.go", out.GoPkg).Imports().Render(); got != "" {
+		t.Errorf("Render() of a second generated file = %q, want no imports", got)
+	}
+}
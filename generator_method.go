@@ -15,7 +15,11 @@ type Message struct {
 	Outers []string
 	*descriptor.DescriptorProto
 	Fields []*Field
+	// Oneofs 消息中真正的oneof,不包含proto3 optional生成的合成oneof
+	Oneofs []*Oneof
 	Index  int
+	// _Path 消息在SourceCodeInfo中的location path
+	_Path []int32
 }
 
 // _LookupField 根据名称查找字段
@@ -28,6 +32,33 @@ func (m *Message) LookupField(name string) *Field {
 	return nil
 }
 
+// IsMapEntry 判断消息是否是protoc为map<K,V>字段合成的 *Entry 消息,
+// 这种消息在Go中没有对应的类型,生成代码时应该跳过.
+func (m *Message) IsMapEntry() bool {
+	return m.GetOptions().GetMapEntry()
+}
+
+// IsSyntheticOneof 判断下标为index的oneof是否为proto3 optional字段生成的合成oneof
+func (m *Message) IsSyntheticOneof(index int32) bool {
+	for _, fd := range m.GetField() {
+		if fd.OneofIndex != nil && fd.GetOneofIndex() == index {
+			return fd.GetProto3Optional()
+		}
+	}
+	return false
+}
+
+// RealOneofDecl 返回消息中真正的oneof声明,不包含proto3 optional生成的合成oneof
+func (m *Message) RealOneofDecl() []*descriptor.OneofDescriptorProto {
+	var oneofs []*descriptor.OneofDescriptorProto
+	for i, od := range m.GetOneofDecl() {
+		if !m.IsSyntheticOneof(int32(i)) {
+			oneofs = append(oneofs, od)
+		}
+	}
+	return oneofs
+}
+
 // FQMN 返回一个完整的消息名称
 func (m *Message) FQMN() string {
 	components := []string{""}
@@ -39,19 +70,25 @@ func (m *Message) FQMN() string {
 	return strings.Join(components, ".")
 }
 
-// GoType 用于返回一个Go类型
-func (m *Message) GoType(currentPackage string) string {
-	var components []string
-	components = append(components, m.Outers...)
-	components = append(components, m.GetName())
+// GoName 返回不带包名的Go类型名,与protoc-gen-go的命名一致,
+// 例如 Outer.Inner 为 Outer_Inner, Outer.inner_msg 为 OuterInnerMsg
+func (m *Message) GoName() string {
+	return GoCamelCase(_RelativeName(m.Outers, m.GetName()))
+}
 
-	name := strings.Join(components, "_")
+// GoType 用于返回在currentPackage包中引用这个消息的Go类型.
+// 包名是Registry分配的全局包名,与Registry.NewGeneratedFile创建的生成文件的导入管理器分配的包名一致;
+// 需要同时记录导入时使用GeneratedFile.MessageType.
+func (m *Message) GoType(currentPackage string) string {
+	name := m.GoName()
 	if m.File.GoPkg.Path == currentPackage {
 		return name
 	}
-	pkg := m.File.GoPkg.Name
-	if alias := m.File.GoPkg.Alias; alias != "" {
-		pkg = alias
-	}
-	return fmt.Sprintf("%s.%s", pkg, name)
+	return fmt.Sprintf("%s.%s", m.File.GoPkg._LocalName(), name)
+}
+
+// GoTypeIn 返回在使用im管理导入的生成文件中引用这个消息的Go类型,
+// 会同时在im中记录消息所在的包,保证限定名与渲染出的导入一致
+func (m *Message) GoTypeIn(im *ImportManager) string {
+	return im.Qualify(m.File.GoPkg, m.GoName())
 }
@@ -0,0 +1,177 @@
+package gengo
+
+import (
+	"strings"
+
+	"github.com/yuansudong/gengo/casing"
+)
+
+// _GeneratedMethodNames 是protoc-gen-go为消息生成的方法,字段名与它们冲突时会追加下划线
+var _GeneratedMethodNames = []string{
+	"Reset",
+	"String",
+	"ProtoMessage",
+	"Marshal",
+	"Unmarshal",
+	"ExtensionRangeArray",
+	"ExtensionMap",
+	"Descriptor",
+}
+
+// GoCamelCase 与protoc-gen-go的命名规则一致,将proto名称转换为Go标识符.
+// 与Camel的区别是会处理 . 分隔的嵌套名称,例如 Foo.bar_baz 转换为 FooBarBaz, Foo.Bar 转换为 Foo_Bar.
+func GoCamelCase(s string) string {
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '.' && i+1 < len(s) && isASCIILower(s[i+1]):
+			// 跳过 .{{小写字母}} 中的 .
+		case c == '.':
+			b = append(b, '_')
+		case c == '_' && (i == 0 || s[i-1] == '.'):
+			// 开头的 _ 以及 . 之后的 _ 转换为 X,保证以大写字母开头
+			b = append(b, 'X')
+		case c == '_' && i+1 < len(s) && isASCIILower(s[i+1]):
+			// 跳过 _{{小写字母}} 中的 _
+		case isASCIIDigit(c):
+			b = append(b, c)
+		default:
+			if isASCIILower(c) {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+			for ; i+1 < len(s) && isASCIILower(s[i+1]); i++ {
+				b = append(b, s[i+1])
+			}
+		}
+	}
+	return string(b)
+}
+
+// _RelativeName 返回去掉proto包名之后的名称,例如 Outer.Inner
+func _RelativeName(outers []string, name string) string {
+	return strings.Join(append(append([]string(nil), outers...), name), ".")
+}
+
+// _ResolveGoNames 按protoc-gen-go的规则为消息的字段,oneof以及oneof包装结构体分配Go名称.
+// 字段名与生成的方法或者其他字段的getter冲突时追加下划线;
+// oneof包装结构体名与嵌套的消息或枚举冲突时追加下划线.
+func (m *Message) _ResolveGoNames() {
+	used := make(map[string]bool)
+	for _, name := range _GeneratedMethodNames {
+		used[name] = true
+	}
+	makeUnique := func(name string, hasGetter bool) string {
+		for used[name] || (hasGetter && used["Get"+name]) {
+			name += "_"
+		}
+		used[name] = true
+		used["Get"+name] = hasGetter
+		return name
+	}
+	// 合成的oneof虽然不会生成代码,protoc-gen-go依然会为它们占用名字
+	seenOneofs := make(map[int32]bool)
+	oneofs := m.GetOneofDecl()
+	for _, f := range m.Fields {
+		f._GoName = makeUnique(GoCamelCase(f.GetName()), true)
+		if f.OneofIndex == nil || seenOneofs[f.GetOneofIndex()] {
+			continue
+		}
+		seenOneofs[f.GetOneofIndex()] = true
+		oneofName := makeUnique(GoCamelCase(oneofs[f.GetOneofIndex()].GetName()), false)
+		if f.Oneof != nil {
+			f.Oneof._GoName = oneofName
+		}
+	}
+
+	// 嵌套类型的Go名称由完整的相对名称转换而来,例如 Outer.inner 是 OuterInner,
+	// 所以名为inner的嵌套类型不会与oneof字段Inner的包装结构体 Outer_Inner 冲突
+	outers := append(append([]string(nil), m.Outers...), m.GetName())
+	nested := make(map[string]bool)
+	for _, md := range m.GetNestedType() {
+		nested[GoCamelCase(_RelativeName(outers, md.GetName()))] = true
+	}
+	for _, ed := range m.GetEnumType() {
+		nested[GoCamelCase(_RelativeName(outers, ed.GetName()))] = true
+	}
+	for _, f := range m.Fields {
+		if f.Oneof == nil {
+			continue
+		}
+		name := m.GoName() + "_" + f._GoName
+		for nested[name] {
+			name += "_"
+		}
+		f._WrapperName = name
+	}
+}
+
+// GoName 返回字段在protoc-gen-go生成的结构体中的字段名
+func (f *Field) GoName() string {
+	return f._GoName
+}
+
+// GetterName 返回protoc-gen-go为字段生成的getter方法名
+func (f *Field) GetterName() string {
+	return "Get" + f._GoName
+}
+
+// JSONName 返回字段在proto3 JSON中的名称,优先使用protoc填写的json_name
+func (f *Field) JSONName() string {
+	if f.JsonName != nil {
+		return f.GetJsonName()
+	}
+	return casing.JSONName(f.GetName())
+}
+
+// LowerCamelName 返回首字母小写的驼峰字段名
+func (f *Field) LowerCamelName() string {
+	return casing.LowerCamel(f.GetName())
+}
+
+// SnakeName 返回小写下划线风格的字段名
+func (f *Field) SnakeName() string {
+	return casing.Snake(f.GetName())
+}
+
+// ScreamingSnakeName 返回大写下划线风格的字段名,通常用于生成常量
+func (f *Field) ScreamingSnakeName() string {
+	return casing.ScreamingSnake(f.GetName())
+}
+
+// KebabName 返回小写中划线风格的字段名,通常用于命令行参数或HTTP头
+func (f *Field) KebabName() string {
+	return casing.Kebab(f.GetName())
+}
+
+// GoName 返回服务的Go名称
+func (s *Service) GoName() string {
+	return GoCamelCase(s.GetName())
+}
+
+// ClientName 返回protoc-gen-go-grpc为服务生成的客户端接口名
+func (s *Service) ClientName() string {
+	return s.GoName() + "Client"
+}
+
+// ServerName 返回protoc-gen-go-grpc为服务生成的服务端接口名
+func (s *Service) ServerName() string {
+	return s.GoName() + "Server"
+}
+
+// GoName 返回RPC方法的Go名称
+func (m *Method) GoName() string {
+	return GoCamelCase(m.GetName())
+}
+
+// ValueGoName 返回枚举值对应的Go常量名.
+// 顶层枚举的值命名为 枚举名_值名, 嵌套在消息中的枚举的值命名为 消息名_值名,
+// 与protoc-gen-go一致,值名不做驼峰转换.
+func (e *Enum) ValueGoName(valueName string) string {
+	if len(e.Outers) == 0 {
+		return e.GoName() + "_" + valueName
+	}
+	outers := e.Outers[:len(e.Outers)-1]
+	return GoCamelCase(_RelativeName(outers, e.Outers[len(e.Outers)-1])) + "_" + valueName
+}
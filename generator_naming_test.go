@@ -0,0 +1,172 @@
+package gengo
+
+import (
+	"testing"
+
+	descriptor "github.com/yuansudong/gengo/descriptor"
+	plugin "github.com/yuansudong/gengo/plugin"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestGoCamelCase(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want string
+	}{
+		{in: "foo_bar", want: "FooBar"},
+		{in: "_foo", want: "XFoo"},
+		{in: "foo__bar", want: "Foo_Bar"},
+		{in: "foo_1bar", want: "Foo_1Bar"},
+		{in: "Outer.inner", want: "OuterInner"},
+		{in: "Outer.Inner", want: "Outer_Inner"},
+		{in: "Outer._x", want: "Outer_XX"},
+		{in: "HTTPServer", want: "HTTPServer"},
+	} {
+		if got := GoCamelCase(tc.in); got != tc.want {
+			t.Errorf("GoCamelCase(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+// _NamingField 返回一个字段, oneof 为非负数时字段属于这个下标的oneof
+func _NamingField(name string, number int32, oneof int32) *descriptor.FieldDescriptorProto {
+	f := &descriptor.FieldDescriptorProto{
+		Name:     proto.String(name),
+		Number:   proto.Int32(number),
+		Type:     descriptor.FieldDescriptorProto_TYPE_STRING.Enum(),
+		Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		JsonName: proto.String(name),
+	}
+	if oneof >= 0 {
+		f.OneofIndex = proto.Int32(oneof)
+	}
+	return f
+}
+
+// _NamingFile 返回一个包含各种命名冲突的proto3文件
+func _NamingFile() *descriptor.FileDescriptorProto {
+	optional := _NamingField("opt", 9, 1)
+	optional.Proto3Optional = proto.Bool(true)
+	return &descriptor.FileDescriptorProto{
+		Name:    proto.String("naming.proto"),
+		Package: proto.String("naming"),
+		Syntax:  proto.String("proto3"),
+		Options: &descriptor.FileOptions{GoPackage: proto.String("example.com/naming")},
+		MessageType: []*descriptor.DescriptorProto{
+			{
+				// 嵌套消息inner的Go名称是OuterInner,不与包装结构体Outer_Inner冲突
+				Name:       proto.String("Outer"),
+				Field:      []*descriptor.FieldDescriptorProto{_NamingField("Inner", 1, 0)},
+				OneofDecl:  []*descriptor.OneofDescriptorProto{{Name: proto.String("kind")}},
+				NestedType: []*descriptor.DescriptorProto{{Name: proto.String("inner")}},
+			},
+			{
+				// 嵌套消息Inner与枚举Kind的Go名称与包装结构体冲突
+				Name: proto.String("Clash"),
+				Field: []*descriptor.FieldDescriptorProto{
+					_NamingField("inner", 1, 0),
+					_NamingField("kind", 2, 0),
+				},
+				OneofDecl:  []*descriptor.OneofDescriptorProto{{Name: proto.String("choice")}},
+				NestedType: []*descriptor.DescriptorProto{{Name: proto.String("Inner")}},
+				EnumType: []*descriptor.EnumDescriptorProto{{
+					Name:  proto.String("Kind"),
+					Value: []*descriptor.EnumValueDescriptorProto{{Name: proto.String("KIND_UNSPECIFIED"), Number: proto.Int32(0)}},
+				}},
+			},
+			{
+				// 字段名与生成的方法,getter以及oneof冲突
+				Name: proto.String("Methods"),
+				Field: []*descriptor.FieldDescriptorProto{
+					_NamingField("reset", 1, -1),
+					_NamingField("get_name", 2, -1),
+					_NamingField("name", 3, -1),
+					_NamingField("descriptor", 4, 0),
+					_NamingField("value", 5, 0),
+					optional,
+				},
+				OneofDecl: []*descriptor.OneofDescriptorProto{
+					{Name: proto.String("get_value")},
+					{Name: proto.String("_opt")},
+				},
+				NestedType: []*descriptor.DescriptorProto{{
+					Name:      proto.String("Deep"),
+					Field:     []*descriptor.FieldDescriptorProto{_NamingField("x_y", 1, 0)},
+					OneofDecl: []*descriptor.OneofDescriptorProto{{Name: proto.String("x")}},
+					NestedType: []*descriptor.DescriptorProto{{
+						Name: proto.String("XY"),
+					}},
+				}},
+			},
+		},
+	}
+}
+
+// _ProtogenMessages 返回protoc-gen-go为fd生成的所有消息,按完整名称索引
+func _ProtogenMessages(t *testing.T, fd *descriptor.FileDescriptorProto) map[string]*protogen.Message {
+	t.Helper()
+	buf, err := proto.Marshal(&plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{fd.GetName()},
+		ProtoFile:      []*descriptor.FileDescriptorProto{fd},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := new(pluginpb.CodeGeneratorRequest)
+	if err := proto.Unmarshal(buf, req); err != nil {
+		t.Fatal(err)
+	}
+	gen, err := protogen.Options{}.New(req)
+	if err != nil {
+		t.Fatalf("protogen failed: %v", err)
+	}
+	msgs := make(map[string]*protogen.Message)
+	var walk func([]*protogen.Message)
+	walk = func(ms []*protogen.Message) {
+		for _, m := range ms {
+			msgs["."+string(m.Desc.FullName())] = m
+			walk(m.Messages)
+		}
+	}
+	walk(gen.Files[0].Messages)
+	return msgs
+}
+
+func TestGoNamesMatchProtogen(t *testing.T) {
+	fd := _NamingFile()
+	want := _ProtogenMessages(t, fd)
+	r := NewRegistry()
+	if err := r.Load(&plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{fd.GetName()},
+		ProtoFile:      []*descriptor.FileDescriptorProto{fd},
+	}); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	for fqmn, pm := range want {
+		m, err := r.LookupMsg("", fqmn)
+		if err != nil {
+			t.Errorf("LookupMsg(%s) failed: %v", fqmn, err)
+			continue
+		}
+		if got := m.GoName(); got != pm.GoIdent.GoName {
+			t.Errorf("%s: GoName() = %q, protogen %q", fqmn, got, pm.GoIdent.GoName)
+		}
+		for i, pf := range pm.Fields {
+			f := m.Fields[i]
+			if got := f.GoName(); got != pf.GoName {
+				t.Errorf("%s.%s: GoName() = %q, protogen %q", fqmn, f.GetName(), got, pf.GoName)
+			}
+			if pf.Oneof == nil || pf.Oneof.Desc.IsSynthetic() {
+				continue
+			}
+			if got := f.OneofWrapperName(); got != pf.GoIdent.GoName {
+				t.Errorf("%s.%s: OneofWrapperName() = %q, protogen %q", fqmn, f.GetName(), got, pf.GoIdent.GoName)
+			}
+			if got := f.Oneof.GoName(); got != pf.Oneof.GoName {
+				t.Errorf("%s.%s: Oneof.GoName() = %q, protogen %q", fqmn, f.GetName(), got, pf.Oneof.GoName)
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package gengo
+
+import (
+	descriptor "github.com/yuansudong/gengo/descriptor"
+)
+
+// Oneof 描述消息中的一个oneof声明,不包含proto3 optional生成的合成oneof
+type Oneof struct {
+	// Message 这个oneof属于哪个消息
+	Message *Message
+	*descriptor.OneofDescriptorProto
+	// Index 在消息的OneofDecl中的下标
+	Index int
+	// Fields 属于这个oneof的字段
+	Fields []*Field
+	// _Path oneof在SourceCodeInfo中的location path
+	_Path []int32
+	// _GoName oneof在Go结构体中的字段名
+	_GoName string
+}
+
+// FQN 返回oneof的完整名称
+func (o *Oneof) FQN() string {
+	return o.Message.FQMN() + "." + o.GetName()
+}
+
+// GoName 返回oneof在Go结构体中的字段名
+func (o *Oneof) GoName() string {
+	return o._GoName
+}
+
+// GoInterfaceName 返回protoc-gen-go为oneof生成的接口类型名,例如 isFoo_Bar
+func (o *Oneof) GoInterfaceName() string {
+	return "is" + o.Message.GoName() + "_" + o.GoName()
+}
+
+// OneofWrapperName 返回oneof成员字段对应的包装结构体名,例如 Foo_Baz.
+// 与protoc-gen-go一致,名字和嵌套的消息或枚举冲突时会追加下划线.
+// 字段不属于oneof时返回空字符串.
+func (f *Field) OneofWrapperName() string {
+	return f._WrapperName
+}
@@ -0,0 +1,156 @@
+package gengo
+
+import (
+	"reflect"
+	"testing"
+
+	descriptor "github.com/yuansudong/gengo/descriptor"
+	plugin "github.com/yuansudong/gengo/plugin"
+	"google.golang.org/protobuf/proto"
+)
+
+// _OneofFile 返回一个proto3文件:
+//
+//	message Msg {
+//	  int32 id = 1;
+//	  oneof choice { string a = 2; string b = 3; }
+//	  optional string opt = 4;
+//	  oneof other { string c = 5; }
+//	}
+//	message First {
+//	  optional string opt = 1;
+//	  oneof kind { string x = 2; }
+//	}
+func _OneofFile() *descriptor.FileDescriptorProto {
+	optional := func(name string, number, oneof int32) *descriptor.FieldDescriptorProto {
+		f := _NamingField(name, number, oneof)
+		f.Proto3Optional = proto.Bool(true)
+		return f
+	}
+	oneofs := func(names ...string) []*descriptor.OneofDescriptorProto {
+		var decls []*descriptor.OneofDescriptorProto
+		for _, name := range names {
+			decls = append(decls, &descriptor.OneofDescriptorProto{Name: proto.String(name)})
+		}
+		return decls
+	}
+	return &descriptor.FileDescriptorProto{
+		Name:    proto.String("oneof.proto"),
+		Package: proto.String("oneof"),
+		Syntax:  proto.String("proto3"),
+		Options: &descriptor.FileOptions{GoPackage: proto.String("example.com/oneof")},
+		MessageType: []*descriptor.DescriptorProto{
+			{
+				Name: proto.String("Msg"),
+				Field: []*descriptor.FieldDescriptorProto{
+					_NamingField("id", 1, -1),
+					_NamingField("a", 2, 0),
+					_NamingField("b", 3, 0),
+					optional("opt", 4, 1),
+					_NamingField("c", 5, 2),
+				},
+				OneofDecl: oneofs("choice", "_opt", "other"),
+			},
+			{
+				// 合成的oneof排在真正的oneof之前时,Oneof.Index依然是OneofDecl中的下标
+				Name:      proto.String("First"),
+				Field:     []*descriptor.FieldDescriptorProto{optional("opt", 1, 0), _NamingField("x", 2, 1)},
+				OneofDecl: oneofs("_opt", "kind"),
+			},
+		},
+	}
+}
+
+func TestOneofs(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Load(&plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"oneof.proto"},
+		ProtoFile:      []*descriptor.FileDescriptorProto{_OneofFile()},
+	}); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	type oneof struct {
+		FQN, GoName, Interface string
+		Index                  int
+		Fields, Wrappers       []string
+	}
+	for _, tc := range []struct {
+		msg       string
+		oneofs    []oneof
+		synthetic []int32
+		real      []string
+	}{
+		{
+			msg: ".oneof.Msg",
+			oneofs: []oneof{
+				{FQN: ".oneof.Msg.choice", GoName: "Choice", Interface: "isMsg_Choice", Index: 0, Fields: []string{"a", "b"}, Wrappers: []string{"Msg_A", "Msg_B"}},
+				{FQN: ".oneof.Msg.other", GoName: "Other", Interface: "isMsg_Other", Index: 2, Fields: []string{"c"}, Wrappers: []string{"Msg_C"}},
+			},
+			synthetic: []int32{1},
+			real:      []string{"choice", "other"},
+		},
+		{
+			msg: ".oneof.First",
+			oneofs: []oneof{
+				{FQN: ".oneof.First.kind", GoName: "Kind", Interface: "isFirst_Kind", Index: 1, Fields: []string{"x"}, Wrappers: []string{"First_X"}},
+			},
+			synthetic: []int32{0},
+			real:      []string{"kind"},
+		},
+	} {
+		t.Run(tc.msg, func(t *testing.T) {
+			m, err := r.LookupMsg("", tc.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []oneof
+			for _, o := range m.Oneofs {
+				if o.Message != m {
+					t.Errorf("%s.Message = %s", o.GetName(), o.Message.FQMN())
+				}
+				g := oneof{FQN: o.FQN(), GoName: o.GoName(), Interface: o.GoInterfaceName(), Index: o.Index}
+				for _, f := range o.Fields {
+					if f.Oneof != o || !f.InRealOneof() {
+						t.Errorf("%s.Oneof = %v, want %s", f.GetName(), f.Oneof, o.GetName())
+					}
+					g.Fields = append(g.Fields, f.GetName())
+					g.Wrappers = append(g.Wrappers, f.OneofWrapperName())
+				}
+				got = append(got, g)
+			}
+			if !reflect.DeepEqual(got, tc.oneofs) {
+				t.Errorf("Oneofs = %+v, want %+v", got, tc.oneofs)
+			}
+
+			var synthetic []int32
+			for i := range m.GetOneofDecl() {
+				if m.IsSyntheticOneof(int32(i)) {
+					synthetic = append(synthetic, int32(i))
+				}
+			}
+			if !reflect.DeepEqual(synthetic, tc.synthetic) {
+				t.Errorf("synthetic oneofs = %v, want %v", synthetic, tc.synthetic)
+			}
+			if m.IsSyntheticOneof(int32(len(m.GetOneofDecl()))) {
+				t.Errorf("IsSyntheticOneof() reported an index out of range")
+			}
+			var real []string
+			for _, od := range m.RealOneofDecl() {
+				real = append(real, od.GetName())
+			}
+			if !reflect.DeepEqual(real, tc.real) {
+				t.Errorf("RealOneofDecl() = %q, want %q", real, tc.real)
+			}
+
+			// proto3 optional字段不属于任何oneof,也没有包装结构体
+			opt := m.LookupField("opt")
+			if !opt.IsProto3Optional() || opt.InRealOneof() || opt.Oneof != nil || opt.OneofWrapperName() != "" {
+				t.Errorf("opt: proto3 optional %v, in real oneof %v, wrapper %q", opt.IsProto3Optional(), opt.InRealOneof(), opt.OneofWrapperName())
+			}
+		})
+	}
+	m, _ := r.LookupMsg("", ".oneof.Msg")
+	if id := m.LookupField("id"); id.InRealOneof() || id.IsProto3Optional() || id.OneofWrapperName() != "" {
+		t.Errorf("id: in real oneof %v, proto3 optional %v, wrapper %q", id.InRealOneof(), id.IsProto3Optional(), id.OneofWrapperName())
+	}
+}
@@ -0,0 +1,128 @@
+package gengo
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// GetOption 从options中读取自定义扩展xt的值,没有设置时返回扩展的默认值.
+// 插件与proto文件使用的扩展定义不在同一个描述集中时,扩展会以未知字段的形式
+// 出现在options里,这里会用xt重新解析这些未知字段.
+func GetOption(opts proto.Message, xt protoreflect.ExtensionType) (interface{}, error) {
+	value, _, err := _LookupOption(opts, xt)
+	return value, err
+}
+
+// HasOption 判断options中是否设置了自定义扩展xt.
+// 扩展以未知字段的形式出现但无法按xt解码时返回错误,不会当作没有设置.
+func HasOption(opts proto.Message, xt protoreflect.ExtensionType) (bool, error) {
+	_, ok, err := _LookupOption(opts, xt)
+	if err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+// _LookupOption 查找扩展的值,第二个返回值表示是否设置了这个扩展
+func _LookupOption(opts proto.Message, xt protoreflect.ExtensionType) (interface{}, bool, error) {
+	def := xt.InterfaceOf(xt.Zero())
+	if opts == nil || !opts.ProtoReflect().IsValid() {
+		return def, false, nil
+	}
+	xd := xt.TypeDescriptor()
+	if want, got := xd.ContainingMessage().FullName(), opts.ProtoReflect().Descriptor().FullName(); want != got {
+		return nil, false, fmt.Errorf("extension %s extends %s, not %s", xd.FullName(), want, got)
+	}
+	if proto.HasExtension(opts, xt) {
+		return proto.GetExtension(opts, xt), true, nil
+	}
+	unknown := opts.ProtoReflect().GetUnknown()
+	if len(unknown) == 0 {
+		return def, false, nil
+	}
+	types := new(protoregistry.Types)
+	if err := types.RegisterExtension(xt); err != nil {
+		return nil, false, err
+	}
+	decoded := opts.ProtoReflect().New().Interface()
+	if err := (proto.UnmarshalOptions{Resolver: types}).Unmarshal(unknown, decoded); err != nil {
+		return nil, false, fmt.Errorf("failed to decode extension %s: %v", xd.FullName(), err)
+	}
+	if !proto.HasExtension(decoded, xt) {
+		return def, false, nil
+	}
+	return proto.GetExtension(decoded, xt), true, nil
+}
+
+// Option 读取文件的自定义扩展
+func (f *File) Option(xt protoreflect.ExtensionType) (interface{}, error) {
+	return GetOption(f.GetOptions(), xt)
+}
+
+// HasOption 判断文件是否设置了自定义扩展
+func (f *File) HasOption(xt protoreflect.ExtensionType) (bool, error) {
+	return HasOption(f.GetOptions(), xt)
+}
+
+// Option 读取消息的自定义扩展
+func (m *Message) Option(xt protoreflect.ExtensionType) (interface{}, error) {
+	return GetOption(m.GetOptions(), xt)
+}
+
+// HasOption 判断消息是否设置了自定义扩展
+func (m *Message) HasOption(xt protoreflect.ExtensionType) (bool, error) {
+	return HasOption(m.GetOptions(), xt)
+}
+
+// Option 读取字段的自定义扩展
+func (f *Field) Option(xt protoreflect.ExtensionType) (interface{}, error) {
+	return GetOption(f.GetOptions(), xt)
+}
+
+// HasOption 判断字段是否设置了自定义扩展
+func (f *Field) HasOption(xt protoreflect.ExtensionType) (bool, error) {
+	return HasOption(f.GetOptions(), xt)
+}
+
+// Option 读取枚举的自定义扩展
+func (e *Enum) Option(xt protoreflect.ExtensionType) (interface{}, error) {
+	return GetOption(e.GetOptions(), xt)
+}
+
+// HasOption 判断枚举是否设置了自定义扩展
+func (e *Enum) HasOption(xt protoreflect.ExtensionType) (bool, error) {
+	return HasOption(e.GetOptions(), xt)
+}
+
+// Option 读取枚举值的自定义扩展
+func (v *EnumValue) Option(xt protoreflect.ExtensionType) (interface{}, error) {
+	return GetOption(v.GetOptions(), xt)
+}
+
+// HasOption 判断枚举值是否设置了自定义扩展
+func (v *EnumValue) HasOption(xt protoreflect.ExtensionType) (bool, error) {
+	return HasOption(v.GetOptions(), xt)
+}
+
+// Option 读取服务的自定义扩展
+func (s *Service) Option(xt protoreflect.ExtensionType) (interface{}, error) {
+	return GetOption(s.GetOptions(), xt)
+}
+
+// HasOption 判断服务是否设置了自定义扩展
+func (s *Service) HasOption(xt protoreflect.ExtensionType) (bool, error) {
+	return HasOption(s.GetOptions(), xt)
+}
+
+// Option 读取RPC方法的自定义扩展
+func (m *Method) Option(xt protoreflect.ExtensionType) (interface{}, error) {
+	return GetOption(m.GetOptions(), xt)
+}
+
+// HasOption 判断RPC方法是否设置了自定义扩展
+func (m *Method) HasOption(xt protoreflect.ExtensionType) (bool, error) {
+	return HasOption(m.GetOptions(), xt)
+}
@@ -0,0 +1,119 @@
+package gengo
+
+import (
+	"testing"
+
+	descriptor "github.com/yuansudong/gengo/descriptor"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// _OptionExtensions 返回扩展MessageOptions的 string label = 50001 与 int32 level = 50002.
+// 扩展没有注册到全局的registry中,与插件不认识proto文件使用的扩展时的情形相同.
+func _OptionExtensions(t *testing.T) (label, level protoreflect.ExtensionType) {
+	t.Helper()
+	ext := func(name string, number int32, typ descriptor.FieldDescriptorProto_Type) *descriptor.FieldDescriptorProto {
+		return &descriptor.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(number),
+			Type:     typ.Enum(),
+			Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Extendee: proto.String(".google.protobuf.MessageOptions"),
+		}
+	}
+	fd, err := protodesc.NewFile(&descriptor.FileDescriptorProto{
+		Name:       proto.String("opts.proto"),
+		Package:    proto.String("opts"),
+		Syntax:     proto.String("proto2"),
+		Dependency: []string{"google/protobuf/descriptor.proto"},
+		Extension: []*descriptor.FieldDescriptorProto{
+			ext("label", 50001, descriptor.FieldDescriptorProto_TYPE_STRING),
+			ext("level", 50002, descriptor.FieldDescriptorProto_TYPE_INT32),
+		},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dynamicpb.NewExtensionType(fd.Extensions().Get(0)), dynamicpb.NewExtensionType(fd.Extensions().Get(1))
+}
+
+// _UnknownOptions 返回以未知字段的形式携带raw的MessageOptions
+func _UnknownOptions(raw []byte) *descriptor.MessageOptions {
+	opts := &descriptor.MessageOptions{Deprecated: proto.Bool(true)}
+	opts.ProtoReflect().SetUnknown(raw)
+	return opts
+}
+
+func TestGetOptionFromUnknownFields(t *testing.T) {
+	label, level := _OptionExtensions(t)
+	raw := protowire.AppendString(protowire.AppendTag(nil, 50001, protowire.BytesType), "hello")
+	raw = protowire.AppendVarint(protowire.AppendTag(raw, 60000, protowire.VarintType), 7)
+	opts := _UnknownOptions(raw)
+
+	if got, err := GetOption(opts, label); err != nil || got != "hello" {
+		t.Errorf("GetOption(label) = %v, %v; want hello", got, err)
+	}
+	if ok, err := HasOption(opts, label); err != nil || !ok {
+		t.Errorf("HasOption(label) = %v, %v; want true", ok, err)
+	}
+	// 没有设置的扩展返回默认值
+	if got, err := GetOption(opts, level); err != nil || got != int32(0) {
+		t.Errorf("GetOption(level) = %v, %v; want 0", got, err)
+	}
+	if ok, err := HasOption(opts, level); err != nil || ok {
+		t.Errorf("HasOption(level) = %v, %v; want false", ok, err)
+	}
+	m := &Message{DescriptorProto: &descriptor.DescriptorProto{Name: proto.String("M"), Options: opts}}
+	if ok, err := m.HasOption(label); err != nil || !ok {
+		t.Errorf("Message.HasOption(label) = %v, %v; want true", ok, err)
+	}
+}
+
+func TestGetOptionFromKnownExtension(t *testing.T) {
+	label, _ := _OptionExtensions(t)
+	opts := &descriptor.MessageOptions{}
+	proto.SetExtension(opts, label, "set")
+	if got, err := GetOption(opts, label); err != nil || got != "set" {
+		t.Errorf("GetOption(label) = %v, %v; want set", got, err)
+	}
+	if ok, err := HasOption(opts, label); err != nil || !ok {
+		t.Errorf("HasOption(label) = %v, %v; want true", ok, err)
+	}
+}
+
+func TestHasOptionMalformed(t *testing.T) {
+	label, level := _OptionExtensions(t)
+	for _, tc := range []struct {
+		name string
+		xt   protoreflect.ExtensionType
+		raw  []byte
+	}{
+		{name: "truncated string", xt: label, raw: protowire.AppendTag(nil, 50001, protowire.BytesType)},
+		{name: "string longer than input", xt: label, raw: append(protowire.AppendTag(nil, 50001, protowire.BytesType), 0x05, 'a')},
+		{name: "truncated varint", xt: level, raw: append(protowire.AppendTag(nil, 50002, protowire.VarintType), 0x80)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := _UnknownOptions(tc.raw)
+			if ok, err := HasOption(opts, tc.xt); err == nil {
+				t.Errorf("HasOption() = %v, nil; want error", ok)
+			}
+			if got, err := GetOption(opts, tc.xt); err == nil {
+				t.Errorf("GetOption() = %v, nil; want error", got)
+			}
+		})
+	}
+}
+
+func TestOptionNilAndWrongContainer(t *testing.T) {
+	label, _ := _OptionExtensions(t)
+	if ok, err := HasOption((*descriptor.MessageOptions)(nil), label); ok || err != nil {
+		t.Errorf("HasOption(nil) = %v, %v; want false, nil", ok, err)
+	}
+	if _, err := HasOption(&descriptor.FieldOptions{}, label); err == nil {
+		t.Errorf("HasOption() on FieldOptions succeeded, want error for an extension of MessageOptions")
+	}
+}
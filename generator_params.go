@@ -0,0 +1,207 @@
+package gengo
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ParamImportPrefix 对应 Registry.SetPrefix 的插件参数
+	ParamImportPrefix = "import_prefix"
+	// ParamImportPath 对应 Registry.SetImportPath 的插件参数
+	ParamImportPath = "import_path"
+	// ParamPaths 对应 Registry.SetPathType 的插件参数, 取值为 import 或 source_relative
+	ParamPaths = "paths"
+	// ParamModule 对应 Registry.SetModule 的插件参数
+	ParamModule = "module"
+	// ParamMultiPackage 对应 Registry.SetMultiPackage 的插件参数
+	ParamMultiPackage = "multi_package"
+)
+
+// _ReservedParams 由 Registry.ApplyParams 处理的参数
+var _ReservedParams = map[string]bool{
+	ParamImportPrefix:     true,
+	ParamImportPath:       true,
+	ParamPaths:            true,
+	ParamModule:           true,
+	ParamMultiPackage:     true,
+	ParamTemplates:        true,
+	ParamDumpRequest:      true,
+	ParamWarningsAsErrors: true,
+}
+
+// Params 描述从 CodeGeneratorRequest.Parameter 中解析出来的插件参数
+type Params struct {
+	// _Keys 按出现的顺序记录参数名,不重复
+	_Keys []string
+	// _Values 参数名到参数值的映射,同名参数会按顺序累加
+	_Values map[string][]string
+	// PkgMap 由 M<file>=<gopkg> 形式的参数构成的映射
+	PkgMap map[string]string
+}
+
+// ParseParams 用于解析protoc传递给插件的参数.
+// 参数之间以逗号分隔,每一项为 key=value 或者 key,
+// 后者等价于 key=true. 形如 Mfoo.proto=example.com/foo 的参数会进入 PkgMap.
+func ParseParams(param string) (*Params, error) {
+	p := &Params{
+		_Values: make(map[string][]string),
+		PkgMap:  make(map[string]string),
+	}
+	for _, item := range strings.Split(param, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, value := item, "true"
+		if i := strings.IndexByte(item, '='); i >= 0 {
+			key, value = item[:i], item[i+1:]
+		}
+		if key == "" {
+			return nil, fmt.Errorf("invalid parameter %q: empty key", item)
+		}
+		if strings.HasPrefix(key, "M") && len(key) > 1 {
+			if value == "" {
+				return nil, fmt.Errorf("invalid parameter %q: empty go package", item)
+			}
+			p.PkgMap[key[1:]] = value
+			continue
+		}
+		if _, ok := p._Values[key]; !ok {
+			p._Keys = append(p._Keys, key)
+		}
+		p._Values[key] = append(p._Values[key], value)
+	}
+	return p, nil
+}
+
+// Keys 按出现顺序返回所有的参数名,不包含 M 参数
+func (p *Params) Keys() []string {
+	return append([]string(nil), p._Keys...)
+}
+
+// Has 判断是否设置了某个参数
+func (p *Params) Has(key string) bool {
+	_, ok := p._Values[key]
+	return ok
+}
+
+// Get 返回参数的值,参数重复出现时返回最后一个
+func (p *Params) Get(key string) (string, bool) {
+	vals := p._Values[key]
+	if len(vals) == 0 {
+		return "", false
+	}
+	return vals[len(vals)-1], true
+}
+
+// GetAll 返回某个参数的所有值
+func (p *Params) GetAll(key string) []string {
+	return append([]string(nil), p._Values[key]...)
+}
+
+// Bool 将参数解析为布尔值,没有设置时返回false
+func (p *Params) Bool(key string) (bool, error) {
+	val, ok := p.Get(key)
+	if !ok {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, fmt.Errorf("invalid value %q for parameter %s: %v", val, key, err)
+	}
+	return b, nil
+}
+
+// Bind 将参数绑定到一个结构体指针上.
+// 结构体字段通过 `gengo:"name"` 标签声明参数名,支持string,bool,整数,浮点数
+// 以及它们的切片类型,切片会收集同名参数的所有值. 带标签的字段必须是导出的.
+// import_prefix, import_path, paths, module, multi_package, templates,
+// dump_request, warnings_as_errors 以及 M 参数由gengo处理,
+// 不需要声明; 其余没有对应字段的参数会返回错误.
+func (p *Params) Bind(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("gengo: Bind requires a non-nil pointer to struct, got %T", v)
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+	bound := make(map[string]bool)
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		key := sf.Tag.Get("gengo")
+		if key == "" || key == "-" {
+			continue
+		}
+		if sf.PkgPath != "" {
+			return fmt.Errorf("gengo: field %s.%s for parameter %s is unexported", rt.Name(), sf.Name, key)
+		}
+		bound[key] = true
+		vals, ok := p._Values[key]
+		if !ok {
+			continue
+		}
+		if err := _SetParamValue(rv.Field(i), vals); err != nil {
+			return fmt.Errorf("invalid value for parameter %s: %v", key, err)
+		}
+	}
+	for _, key := range p._Keys {
+		if bound[key] || _ReservedParams[key] {
+			continue
+		}
+		return fmt.Errorf("unknown parameter: %s", key)
+	}
+	return nil
+}
+
+// _SetParamValue 根据字段类型将参数值写入字段
+func _SetParamValue(fv reflect.Value, vals []string) error {
+	if fv.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := _SetScalarParam(slice.Index(i), val); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+	return _SetScalarParam(fv, vals[len(vals)-1])
+}
+
+// _SetScalarParam 将单个参数值写入标量字段
+func _SetScalarParam(fv reflect.Value, val string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(val, 0, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(val, 0, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(val, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
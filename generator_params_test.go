@@ -0,0 +1,187 @@
+package gengo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseParams(t *testing.T) {
+	p, err := ParseParams("paths=source_relative, Mfoo/a.proto=example.com/foo,plugins,tag=a,tag=b,")
+	if err != nil {
+		t.Fatalf("ParseParams() failed: %v", err)
+	}
+	if got, want := p.Keys(), []string{"paths", "plugins", "tag"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys() = %q, want %q", got, want)
+	}
+	if got, _ := p.Get("paths"); got != "source_relative" {
+		t.Errorf("Get(paths) = %q, want source_relative", got)
+	}
+	if got, _ := p.Get("plugins"); got != "true" {
+		t.Errorf("Get(plugins) = %q, want true", got)
+	}
+	if got, _ := p.Get("tag"); got != "b" {
+		t.Errorf("Get(tag) = %q, want the last value b", got)
+	}
+	if got, want := p.GetAll("tag"), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAll(tag) = %q, want %q", got, want)
+	}
+	if got, want := p.PkgMap, map[string]string{"foo/a.proto": "example.com/foo"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("PkgMap = %v, want %v", got, want)
+	}
+	if p.Has("M") || p.Has("missing") {
+		t.Errorf("Has() reported a parameter that was not set")
+	}
+}
+
+func TestParseParamsErrors(t *testing.T) {
+	for _, param := range []string{"=x", "Mfoo.proto="} {
+		if _, err := ParseParams(param); err == nil {
+			t.Errorf("ParseParams(%q) succeeded, want error", param)
+		}
+	}
+}
+
+func TestParamsBool(t *testing.T) {
+	p, err := ParseParams("a,b=false,c=yes")
+	if err != nil {
+		t.Fatalf("ParseParams() failed: %v", err)
+	}
+	for _, tc := range []struct {
+		key     string
+		want    bool
+		wantErr bool
+	}{
+		{key: "a", want: true},
+		{key: "b", want: false},
+		{key: "c", wantErr: true},
+		{key: "missing", want: false},
+	} {
+		got, err := p.Bool(tc.key)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("Bool(%s) = %v, %v; want %v, error %v", tc.key, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestParamsBind(t *testing.T) {
+	var opts struct {
+		Name    string   `gengo:"name"`
+		Verbose bool     `gengo:"verbose"`
+		Level   int      `gengo:"level"`
+		Ratio   float64  `gengo:"ratio"`
+		Tags    []string `gengo:"tag"`
+		Ports   []uint16 `gengo:"port"`
+		Ignored string   `gengo:"-"`
+	}
+	p, err := ParseParams("name=svc,verbose,level=0x10,ratio=0.5,tag=a,tag=b,port=80,port=443,paths=import,Mx.proto=example.com/x")
+	if err != nil {
+		t.Fatalf("ParseParams() failed: %v", err)
+	}
+	if err := p.Bind(&opts); err != nil {
+		t.Fatalf("Bind() failed: %v", err)
+	}
+	if opts.Name != "svc" || !opts.Verbose || opts.Level != 16 || opts.Ratio != 0.5 {
+		t.Errorf("Bind() scalars = %+v", opts)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(opts.Tags, want) {
+		t.Errorf("Bind() Tags = %q, want %q", opts.Tags, want)
+	}
+	if want := []uint16{80, 443}; !reflect.DeepEqual(opts.Ports, want) {
+		t.Errorf("Bind() Ports = %v, want %v", opts.Ports, want)
+	}
+}
+
+func TestParamsBindErrors(t *testing.T) {
+	type good struct {
+		Level int `gengo:"level"`
+	}
+	type unexported struct {
+		level int `gengo:"level"`
+	}
+	var s string
+	for _, tc := range []struct {
+		name  string
+		param string
+		v     interface{}
+		want  string
+	}{
+		{name: "unknown parameter", param: "other=1", v: &good{}, want: "unknown parameter: other"},
+		{name: "invalid value", param: "level=x", v: &good{}, want: "invalid value for parameter level"},
+		{name: "not a struct", param: "", v: &s, want: "pointer to struct"},
+		{name: "not a pointer", param: "", v: good{}, want: "pointer to struct"},
+		{name: "unexported field", param: "level=1", v: &unexported{}, want: "unexported"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := ParseParams(tc.param)
+			if err != nil {
+				t.Fatalf("ParseParams() failed: %v", err)
+			}
+			err = p.Bind(tc.v)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Bind() = %v, want error containing %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestApplyParams(t *testing.T) {
+	p, err := ParseParams("import_prefix=pre/,import_path=example.com/out,paths=source_relative,Mfoo/a.proto=example.com/foo")
+	if err != nil {
+		t.Fatalf("ParseParams() failed: %v", err)
+	}
+	r := NewRegistry()
+	if err := r.ApplyParams(p); err != nil {
+		t.Fatalf("ApplyParams() failed: %v", err)
+	}
+	if r._Prefix != "pre/" || r._ImportPath != "example.com/out" || r._PathType != PathTypeSourceRelative {
+		t.Errorf("ApplyParams() prefix=%q importPath=%q pathType=%v", r._Prefix, r._ImportPath, r._PathType)
+	}
+	if got := r._PkgMap["foo/a.proto"]; got != "example.com/foo" {
+		t.Errorf("ApplyParams() PkgMap[foo/a.proto] = %q, want example.com/foo", got)
+	}
+	if r.Params() != p {
+		t.Errorf("Params() does not return the applied parameters")
+	}
+}
+
+func TestApplyParamsErrors(t *testing.T) {
+	for _, param := range []string{
+		"paths=relative",
+		"module=example.com,paths=source_relative",
+		"multi_package=maybe",
+	} {
+		p, err := ParseParams(param)
+		if err != nil {
+			t.Fatalf("ParseParams(%q) failed: %v", param, err)
+		}
+		if err := NewRegistry().ApplyParams(p); err == nil {
+			t.Errorf("ApplyParams(%q) succeeded, want error", param)
+		}
+	}
+}
+
+func TestApplyParamsKeepsMultiPackage(t *testing.T) {
+	for _, tc := range []struct {
+		param string
+		want  bool
+	}{
+		{param: "", want: true},
+		{param: "paths=import", want: true},
+		{param: "multi_package=false", want: false},
+		{param: "multi_package", want: true},
+	} {
+		p, err := ParseParams(tc.param)
+		if err != nil {
+			t.Fatalf("ParseParams(%q) failed: %v", tc.param, err)
+		}
+		r := NewRegistry()
+		r.SetMultiPackage(true)
+		if err := r.ApplyParams(p); err != nil {
+			t.Fatalf("ApplyParams(%q) failed: %v", tc.param, err)
+		}
+		if r._MultiPackage != tc.want {
+			t.Errorf("ApplyParams(%q) multi package = %v, want %v", tc.param, r._MultiPackage, tc.want)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package gengo
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/scanner"
+	"go/token"
+	"sort"
+	"strings"
+
+	plugin "github.com/yuansudong/gengo/plugin"
+	"google.golang.org/protobuf/proto"
+)
+
+// _SyntaxContextLines 语法错误诊断中错误行前后展示的行数
+const _SyntaxContextLines = 3
+
+// GeneratedFile 缓存一个生成文件的内容,负责缩进,导入管理以及最终的gofmt
+type GeneratedFile struct {
+	// _Name 生成文件的文件名
+	_Name string
+	// _Pkg 生成文件所在的包
+	_Pkg GoPackage
+	// _Header 包声明之前的注释
+	_Header []string
+	// _Imports 生成文件的导入管理器
+	_Imports *ImportManager
+	// _Buf 包声明与导入块之后的代码
+	_Buf bytes.Buffer
+	// _Indent 当前的缩进层级
+	_Indent int
+}
+
+// NewGeneratedFile 创建一个属于pkg包,文件名为name的生成文件
+func NewGeneratedFile(name string, pkg GoPackage) *GeneratedFile {
+	return &GeneratedFile{
+		_Name:    name,
+		_Pkg:     pkg,
+		_Imports: NewImportManager(pkg),
+	}
+}
+
+// NewGeneratedFile 为属于pkg包的输出文件name创建一个生成文件.
+// 每个输出文件有自己的导入管理器,其中已加载的proto包预先分配了Registry的全局包名,
+// 因此 Message.GoType 与 GeneratedFile.MessageType 得到的限定名一致.
+func (r *Registry) NewGeneratedFile(name string, pkg GoPackage) *GeneratedFile {
+	g := NewGeneratedFile(name, pkg)
+	names := make([]string, 0, len(r._Files))
+	for fname := range r._Files {
+		names = append(names, fname)
+	}
+	sort.Strings(names)
+	for _, fname := range names {
+		f := r._Files[fname]
+		g._Imports.Assign(f.GoPkg.Path, f.GoPkg._LocalName())
+	}
+	return g
+}
+
+// Name 返回生成文件的文件名
+func (g *GeneratedFile) Name() string {
+	return g._Name
+}
+
+// Imports 返回生成文件的导入管理器
+func (g *GeneratedFile) Imports() *ImportManager {
+	return g._Imports
+}
+
+// SetHeader 设置包声明之前的注释,每个参数是一行,不需要带 // 前缀
+func (g *GeneratedFile) SetHeader(lines ...string) {
+	g._Header = lines
+}
+
+// In 增加一级缩进
+func (g *GeneratedFile) In() {
+	g._Indent++
+}
+
+// Out 减少一级缩进
+func (g *GeneratedFile) Out() {
+	if g._Indent > 0 {
+		g._Indent--
+	}
+}
+
+// P 将参数依次拼接后作为一行写入,只在行首加上当前的缩进.
+// 参数中的换行原样写入,不会缩进后面的行,因此多行的原始字符串字面量不会被改变.
+func (g *GeneratedFile) P(v ...interface{}) {
+	var line strings.Builder
+	for _, x := range v {
+		fmt.Fprint(&line, x)
+	}
+	if line.Len() > 0 {
+		g._Buf.WriteString(strings.Repeat("\t", g._Indent))
+	}
+	g._Buf.WriteString(line.String())
+	g._Buf.WriteByte('\n')
+}
+
+// Write 将p原样写入,不加缩进也不加换行,实现io.Writer
+func (g *GeneratedFile) Write(p []byte) (int, error) {
+	return g._Buf.Write(p)
+}
+
+// Pf 按格式写入一行
+func (g *GeneratedFile) Pf(format string, args ...interface{}) {
+	g.P(fmt.Sprintf(format, args...))
+}
+
+// Import 记录对pkg的引用并返回本地包名
+func (g *GeneratedFile) Import(pkg GoPackage) string {
+	return g._Imports.Use(pkg)
+}
+
+// QualifiedGoIdent 返回引用pkg中标识符ident的表达式,并记录导入
+func (g *GeneratedFile) QualifiedGoIdent(pkg GoPackage, ident string) string {
+	return g._Imports.Qualify(pkg, ident)
+}
+
+// MessageType 返回引用消息m的Go类型,并记录导入
+func (g *GeneratedFile) MessageType(m *Message) string {
+	return m.GoTypeIn(g._Imports)
+}
+
+// EnumType 返回引用枚举e的Go类型,并记录导入
+func (g *GeneratedFile) EnumType(e *Enum) string {
+	return e.GoTypeIn(g._Imports)
+}
+
+// _Source 拼接出完整的未格式化代码
+func (g *GeneratedFile) _Source() []byte {
+	var src bytes.Buffer
+	for _, line := range g._Header {
+		fmt.Fprintf(&src, "// %s\n", line)
+	}
+	if len(g._Header) > 0 {
+		src.WriteByte('\n')
+	}
+	fmt.Fprintf(&src, "package %s\n\n", g._Pkg.Name)
+	if imports := g._Imports.Render(); imports != "" {
+		src.WriteString(imports)
+		src.WriteByte('\n')
+	}
+	src.Write(g._Buf.Bytes())
+	return src.Bytes()
+}
+
+// Content 返回gofmt之后的代码. 包名不是合法的标识符时返回错误;
+// 生成的代码有语法错误时返回 *SyntaxError,指出出错的行以及上下文.
+func (g *GeneratedFile) Content() ([]byte, error) {
+	if name := g._Pkg.Name; !token.IsIdentifier(name) || name == "_" {
+		return nil, fmt.Errorf("%s: invalid package name %q for %s", g._Name, name, g._Pkg.Path)
+	}
+	src := g._Source()
+	out, err := format.Source(src)
+	if err != nil {
+		return nil, _NewSyntaxError(g._Name, src, err)
+	}
+	return out, nil
+}
+
+// Response 返回可以写入CodeGeneratorResponse的文件
+func (g *GeneratedFile) Response() (*plugin.CodeGeneratorResponse_File, error) {
+	content, err := g.Content()
+	if err != nil {
+		return nil, err
+	}
+	return &plugin.CodeGeneratorResponse_File{
+		Name:    proto.String(g._Name),
+		Content: proto.String(string(content)),
+	}, nil
+}
+
+// SyntaxError 描述生成的代码中的语法错误
+type SyntaxError struct {
+	// File 生成文件的文件名
+	File string
+	// Line 出错的行号,从1开始
+	Line int
+	// Column 出错的列号,从1开始
+	Column int
+	// Msg 错误信息
+	Msg string
+	// Context 出错行附近带行号的代码,出错的行用 > 标出
+	Context string
+}
+
+// Error 实现error接口
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s\n%s", e.File, e.Line, e.Column, e.Msg, e.Context)
+}
+
+// _NewSyntaxError 将go/format返回的错误转换为带上下文的SyntaxError
+func _NewSyntaxError(name string, src []byte, err error) error {
+	list, ok := err.(scanner.ErrorList)
+	if !ok || len(list) == 0 {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	pos := list[0].Pos
+	lines := strings.Split(string(src), "\n")
+	start := pos.Line - _SyntaxContextLines
+	if start < 1 {
+		start = 1
+	}
+	end := pos.Line + _SyntaxContextLines
+	if end > len(lines) {
+		end = len(lines)
+	}
+	var ctx strings.Builder
+	for i := start; i <= end; i++ {
+		marker := " "
+		if i == pos.Line {
+			marker = ">"
+		}
+		fmt.Fprintf(&ctx, "%s%5d | %s\n", marker, i, lines[i-1])
+	}
+	return &SyntaxError{
+		File:    name,
+		Line:    pos.Line,
+		Column:  pos.Column,
+		Msg:     list[0].Msg,
+		Context: strings.TrimSuffix(ctx.String(), "\n"),
+	}
+}
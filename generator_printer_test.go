@@ -0,0 +1,164 @@
+package gengo
+
+import (
+	"strings"
+	"testing"
+)
+
+// _PrinterPkg 是测试中生成文件所在的包
+var _PrinterPkg = GoPackage{Path: "example.com/out", Name: "out"}
+
+func TestGeneratedFileContent(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		write func(g *GeneratedFile)
+		want  string
+	}{
+		{
+			name:  "empty",
+			write: func(g *GeneratedFile) {},
+			want:  "package out\n",
+		},
+		{
+			name: "header and imports",
+			write: func(g *GeneratedFile) {
+				g.SetHeader("Code generated by gengo. DO NOT EDIT.", "source: a.proto")
+				g.P("var _ = ", g.QualifiedGoIdent(GoPackage{Path: "fmt", Name: "fmt"}, "Sprint"))
+				g.P("var _ ", g.QualifiedGoIdent(GoPackage{Path: "example.com/dep", Name: "dep"}, "Msg"))
+			},
+			want: "// Code generated by gengo. DO NOT EDIT.\n// source: a.proto\n\npackage out\n\n" +
+				"import (\n\t\"fmt\"\n\n\t\"example.com/dep\"\n)\n\nvar _ = fmt.Sprint\nvar _ dep.Msg\n",
+		},
+		{
+			name: "indentation",
+			write: func(g *GeneratedFile) {
+				g.P("func f() {")
+				g.In()
+				g.P("if true {")
+				g.In()
+				g.P("return")
+				g.Out()
+				g.P("}")
+				g.Out()
+				// 多余的Out不会产生负的缩进
+				g.Out()
+				g.P("}")
+			},
+			want: "package out\n\nfunc f() {\n\tif true {\n\t\treturn\n\t}\n}\n",
+		},
+		{
+			name: "gofmt",
+			write: func(g *GeneratedFile) {
+				g.Pf("type T struct{ A int;  BB string }")
+			},
+			want: "package out\n\ntype T struct {\n\tA  int\n\tBB string\n}\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGeneratedFile("out.go", _PrinterPkg)
+			tc.write(g)
+			got, err := g.Content()
+			if err != nil {
+				t.Fatalf("Content() failed: %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("Content() =\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGeneratedFileRawStrings(t *testing.T) {
+	g := NewGeneratedFile("out.go", _PrinterPkg)
+	g.P("func f() string {")
+	g.In()
+	// 原始字符串中的行不能被缩进
+	g.P("return `line 1\n  line 2\nline 3`")
+	g.Out()
+	g.P("}")
+	g.P("const c = `")
+	g.Write([]byte("raw\n\tkept\n"))
+	g.P("`")
+	got, err := g.Content()
+	if err != nil {
+		t.Fatalf("Content() failed: %v", err)
+	}
+	want := "package out\n\nfunc f() string {\n\treturn `line 1\n  line 2\nline 3`\n}\n\nconst c = `\nraw\n\tkept\n`\n"
+	if string(got) != want {
+		t.Errorf("Content() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGeneratedFileInvalidPackageName(t *testing.T) {
+	for _, name := range []string{"", "_", "foo-bar", "1foo", "func x"} {
+		g := NewGeneratedFile("out.go", GoPackage{Path: "example.com/out", Name: name})
+		g.P("var x int")
+		_, err := g.Content()
+		if err == nil || !strings.Contains(err.Error(), "invalid package name") {
+			t.Errorf("Content() with package name %q = %v, want invalid package name error", name, err)
+		}
+	}
+}
+
+func TestGeneratedFileSyntaxError(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		lines   []string
+		line    int
+		column  int
+		context string
+	}{
+		{
+			// 第1行是包声明,第2行是空行,代码从第3行开始
+			name:   "middle",
+			lines:  []string{"var a = 1", "var b = 2", "var c = 3", "func f() {", "	x := ", "}", "var d = 4", "var e = 5", "var f = 6", "var g = 7"},
+			line:   8,
+			column: 1,
+			context: "     5 | var c = 3\n     6 | func f() {\n     7 | \tx := \n" +
+				">    8 | }\n     9 | var d = 4\n    10 | var e = 5\n    11 | var f = 6",
+		},
+		{
+			name:    "first line",
+			lines:   []string{"var = 1"},
+			line:    3,
+			column:  5,
+			context: "     1 | package out\n     2 | \n>    3 | var = 1\n     4 | ",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGeneratedFile("out.go", _PrinterPkg)
+			for _, l := range tc.lines {
+				g.P(l)
+			}
+			_, err := g.Content()
+			se, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("Content() = %v, want *SyntaxError", err)
+			}
+			if se.File != "out.go" || se.Line != tc.line || se.Column != tc.column {
+				t.Errorf("SyntaxError at %s:%d:%d, want out.go:%d:%d", se.File, se.Line, se.Column, tc.line, tc.column)
+			}
+			if se.Context != tc.context {
+				t.Errorf("SyntaxError.Context =\n%s\nwant\n%s", se.Context, tc.context)
+			}
+			if !strings.HasPrefix(se.Error(), "out.go:") || !strings.Contains(se.Error(), se.Context) {
+				t.Errorf("Error() = %q, want position and context", se.Error())
+			}
+			if _, err := g.Response(); err == nil {
+				t.Errorf("Response() succeeded for invalid code")
+			}
+		})
+	}
+}
+
+func TestGeneratedFileResponse(t *testing.T) {
+	g := NewGeneratedFile("foo/out.go", _PrinterPkg)
+	g.P("var x   = 1")
+	rsp, err := g.Response()
+	if err != nil {
+		t.Fatalf("Response() failed: %v", err)
+	}
+	if rsp.GetName() != "foo/out.go" || rsp.GetContent() != "package out\n\nvar x = 1\n" {
+		t.Errorf("Response() = %q, %q", rsp.GetName(), rsp.GetContent())
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	descriptor "github.com/yuansudong/gengo/descriptor"
@@ -21,6 +22,12 @@ type Registry struct {
 	// _Files 是所有的文件集合
 	_Files map[string]*File
 
+	// _Services 是所有的服务集合
+	_Services map[string]*Service
+
+	// _Methods 是所有的RPC方法集合
+	_Methods map[string]*Method
+
 	// _prefix 描述一个golang包名的前缀
 	_Prefix string
 
@@ -32,6 +39,27 @@ type Registry struct {
 
 	// _PkgAliases 包的别名集合
 	_PkgAliases map[string]string
+
+	// _Params 插件参数
+	_Params *Params
+
+	// _PathType 生成文件的输出路径模式
+	_PathType PathType
+
+	// _Module 生成文件输出路径需要去掉的模块前缀
+	_Module string
+
+	// _Diags 加载过程中收集的诊断信息
+	_Diags Diagnostics
+
+	// _MultiPackage 是否允许FileToGenerate跨越多个go包
+	_MultiPackage bool
+
+	// _Targets 按FileToGenerate顺序排列的目标文件
+	_Targets []*File
+
+	// _Converters 路径参数使用的转换函数集合
+	_Converters *ConverterSet
 }
 
 // NewRegistry 实例化一个
@@ -40,36 +68,89 @@ func NewRegistry() *Registry {
 		_Msgs:       make(map[string]*Message),
 		_Enums:      make(map[string]*Enum),
 		_Files:      make(map[string]*File),
+		_Services:   make(map[string]*Service),
+		_Methods:    make(map[string]*Method),
 		_PkgMap:     make(map[string]string),
 		_PkgAliases: make(map[string]string),
+		_Converters: DefaultConverterSet(),
 	}
 }
 
 // Load 加载protobuf的services, methods, messages, enumerations.
+// 加载过程中的错误会被收集起来,以 Diagnostics 的形式一起返回.
 func (r *Registry) Load(req *plugin.CodeGeneratorRequest) error {
 	for _, file := range req.GetProtoFile() {
 		r._LoadFile(file)
 	}
+	r._ResolveFields()
 
 	var sTargetPkg string
+	// pkgFiles 多包模式下每个go包第一个出现的目标文件
+	pkgFiles := make(map[string]*File)
 	for _, name := range req.FileToGenerate {
 		target := r._Files[name]
 		if target == nil {
-			return fmt.Errorf("no such file: %s", name)
+			r._Diags.Add(&Diagnostic{File: name, Severity: SeverityError, Message: "no such file"})
+			continue
 		}
-		name := r._PackageIdentityName(target.FileDescriptorProto)
-		if sTargetPkg == "" {
-			sTargetPkg = name
-		} else {
-			if sTargetPkg != name {
-				return fmt.Errorf("inconsistent package names: %s %s", sTargetPkg, name)
+		target.Generate = true
+		r._Targets = append(r._Targets, target)
+		if r._MultiPackage {
+			// 同一个go包中的文件必须使用相同的包名,否则生成的代码无法编译
+			if first, ok := pkgFiles[target.GoPkg.Path]; !ok {
+				pkgFiles[target.GoPkg.Path] = target
+			} else if first.GoPkg.Name != target.GoPkg.Name {
+				r._Diags.Errorf(target, "inconsistent package names for %s: %s (%s) %s", target.GoPkg.Path, first.GoPkg.Name, first.GetName(), target.GoPkg.Name)
 			}
+			continue
 		}
-		if err := r._LoadServices(target); err != nil {
-			return err
+		pkgName := r._PackageIdentityName(target.FileDescriptorProto)
+		if sTargetPkg == "" {
+			sTargetPkg = pkgName
+		} else if sTargetPkg != pkgName {
+			r._Diags.Errorf(target, "inconsistent package names: %s %s (use %s to generate multiple packages)", sTargetPkg, pkgName, ParamMultiPackage)
 		}
 	}
-	return nil
+	// 服务在标记目标文件之后加载,依赖文件中的HTTP映射问题不影响目标文件的生成
+	for _, file := range req.GetProtoFile() {
+		r._LoadServices(r._Files[file.GetName()])
+	}
+	return r._Diags.Err()
+}
+
+// PackageFiles 是属于同一个go包的目标文件
+type PackageFiles struct {
+	// GoPkg 这些文件所在的go包
+	GoPkg GoPackage
+	// Files 属于这个包的目标文件,按FileToGenerate的顺序排列
+	Files []*File
+}
+
+// TargetFiles 按FileToGenerate的顺序返回所有目标文件
+func (r *Registry) TargetFiles() []*File {
+	return append([]*File(nil), r._Targets...)
+}
+
+// Packages 将目标文件按go包分组,按每个包第一次出现在FileToGenerate中的顺序返回.
+// 没有开启多包模式时最多只有一个包; 开启时Load已经检查过同一个包中的文件使用相同的包名.
+func (r *Registry) Packages() []*PackageFiles {
+	var pkgs []*PackageFiles
+	index := make(map[string]*PackageFiles)
+	for _, f := range r._Targets {
+		pf, ok := index[f.GoPkg.Path]
+		if !ok {
+			pf = &PackageFiles{GoPkg: f.GoPkg}
+			index[f.GoPkg.Path] = pf
+			pkgs = append(pkgs, pf)
+		}
+		pf.Files = append(pf.Files, f)
+	}
+	return pkgs
+}
+
+// Diagnostics 返回加载过程中收集的所有诊断信息
+func (r *Registry) Diagnostics() Diagnostics {
+	return r._Diags
 }
 
 // _LoadFile 用于加载文件
@@ -90,52 +171,150 @@ func (r *Registry) _LoadFile(file *descriptor.FileDescriptorProto) {
 	f := &File{
 		FileDescriptorProto: file,
 		GoPkg:               pkg,
+		_PathType:           r._PathType,
+		_Module:             r._Module,
 	}
 
 	r._Files[file.GetName()] = f
-	r._RegisterMsg(f, nil, file.GetMessageType())
-	r._RegisterEnum(f, nil, file.GetEnumType())
+	r._RegisterMsg(f, nil, []int32{_FileMessageTag}, file.GetMessageType())
+	r._RegisterEnum(f, nil, []int32{_FileEnumTag}, file.GetEnumType())
 }
 
-// _RegisterMsg 注册message类型
-func (r *Registry) _RegisterMsg(file *File, outerPath []string, msgs []*descriptor.DescriptorProto) {
+// _RegisterMsg 注册message类型, locPath 是msgs在SourceCodeInfo中的location path
+func (r *Registry) _RegisterMsg(file *File, outerPath []string, locPath []int32, msgs []*descriptor.DescriptorProto) {
 	for i, md := range msgs {
 		m := &Message{
 			File:            file,
 			Outers:          outerPath,
 			DescriptorProto: md,
 			Index:           i,
+			_Path:           _AppendPath(locPath, int32(i)),
 		}
-		for _, fd := range md.GetField() {
-			m.Fields = append(m.Fields, &Field{
+		oneofs := make(map[int32]*Oneof)
+		for j, od := range md.GetOneofDecl() {
+			if m.IsSyntheticOneof(int32(j)) {
+				continue
+			}
+			o := &Oneof{
+				Message:              m,
+				OneofDescriptorProto: od,
+				Index:                j,
+				_Path:                _AppendPath(m._Path, _MessageOneofTag, int32(j)),
+			}
+			oneofs[int32(j)] = o
+			m.Oneofs = append(m.Oneofs, o)
+		}
+		for j, fd := range md.GetField() {
+			f := &Field{
 				Message:              m,
 				FieldDescriptorProto: fd,
-			})
+				_Path:                _AppendPath(m._Path, _MessageFieldTag, int32(j)),
+			}
+			if fd.OneofIndex != nil && !fd.GetProto3Optional() {
+				if o := oneofs[fd.GetOneofIndex()]; o != nil {
+					f.Oneof = o
+					o.Fields = append(o.Fields, f)
+				}
+			}
+			m.Fields = append(m.Fields, f)
 		}
+		m._ResolveGoNames()
 		file.Messages = append(file.Messages, m)
 		r._Msgs[m.FQMN()] = m
 		var outers []string
 		outers = append(outers, outerPath...)
 		outers = append(outers, m.GetName())
-		r._RegisterMsg(file, outers, m.GetNestedType())
-		r._RegisterEnum(file, outers, m.GetEnumType())
+		r._RegisterMsg(file, outers, _AppendPath(m._Path, _MessageNestedTag), m.GetNestedType())
+		r._RegisterEnum(file, outers, _AppendPath(m._Path, _MessageEnumTag), m.GetEnumType())
+		r._ResolveMapFields(m)
 	}
 }
 
-// _RegisterEnum 增加枚举类型
-func (r *Registry) _RegisterEnum(file *File, outerPath []string, enums []*descriptor.EnumDescriptorProto) {
+// _ResolveMapFields 找出消息中的map字段,map对应的 *Entry 消息一定是该消息的嵌套类型
+func (r *Registry) _ResolveMapFields(m *Message) {
+	for _, f := range m.Fields {
+		if f.GetType() != descriptor.FieldDescriptorProto_TYPE_MESSAGE || f.GetLabel() != descriptor.FieldDescriptorProto_LABEL_REPEATED {
+			continue
+		}
+		entry, ok := r._Msgs[f.GetTypeName()]
+		if !ok || !entry.IsMapEntry() {
+			continue
+		}
+		f.FieldMessage = entry
+		f.MapKey = entry.LookupField("key")
+		f.MapValue = entry.LookupField("value")
+	}
+}
+
+// _RegisterEnum 增加枚举类型, locPath 是enums在SourceCodeInfo中的location path
+func (r *Registry) _RegisterEnum(file *File, outerPath []string, locPath []int32, enums []*descriptor.EnumDescriptorProto) {
 	for i, ed := range enums {
 		e := &Enum{
 			File:                file,
 			Outers:              outerPath,
 			EnumDescriptorProto: ed,
 			Index:               i,
+			_Path:               _AppendPath(locPath, int32(i)),
+		}
+		byNumber := make(map[int32]*EnumValue)
+		for j, vd := range ed.GetValue() {
+			v := &EnumValue{
+				Enum:                     e,
+				EnumValueDescriptorProto: vd,
+				Index:                    j,
+				_Path:                    _AppendPath(e._Path, _EnumValueTag, int32(j)),
+			}
+			if first, ok := byNumber[vd.GetNumber()]; ok {
+				v.AliasOf = first
+				if !ed.GetOptions().GetAllowAlias() {
+					r._Diags.Errorf(v, "%s uses the same number %d as %s, but allow_alias is not set", vd.GetName(), vd.GetNumber(), first.GetName())
+				}
+			} else {
+				byNumber[vd.GetNumber()] = v
+			}
+			e.Values = append(e.Values, v)
 		}
 		file.Enums = append(file.Enums, e)
 		r._Enums[e.FQEN()] = e
 	}
 }
 
+// _ResolveFields 在所有文件注册完成后,解析每个消息和枚举类型的字段,
+// 填充Field.FieldMessage和Field.FieldEnum. 无法解析的类型名会记录到诊断信息中.
+func (r *Registry) _ResolveFields() {
+	for _, fqmn := range r._SortedFQMNs() {
+		m := r._Msgs[fqmn]
+		for _, f := range m.Fields {
+			switch f.GetType() {
+			case descriptor.FieldDescriptorProto_TYPE_MESSAGE, descriptor.FieldDescriptorProto_TYPE_GROUP:
+				if f.FieldMessage != nil {
+					continue
+				}
+				fm, err := r.LookupMsg(fqmn, f.GetTypeName())
+				if err != nil {
+					r._Diags.Add(_AsDiagnostic(f, err))
+					continue
+				}
+				f.FieldMessage = fm
+			case descriptor.FieldDescriptorProto_TYPE_ENUM:
+				fe, err := r.LookupEnum(fqmn, f.GetTypeName())
+				if err != nil {
+					r._Diags.Add(_AsDiagnostic(f, err))
+					continue
+				}
+				f.FieldEnum = fe
+			}
+		}
+	}
+}
+
+// _SortedFQMNs 返回排好序的消息名,保证遍历顺序稳定
+func (r *Registry) _SortedFQMNs() []string {
+	keys := r.GetAllFQMNs()
+	sort.Strings(keys)
+	return keys
+}
+
 // LookupMsg 查找Message
 func (r *Registry) LookupMsg(location, name string) (*Message, error) {
 	if strings.HasPrefix(name, ".") {
@@ -184,6 +363,30 @@ func (r *Registry) LookupEnum(location, name string) (*Enum, error) {
 	return nil, fmt.Errorf("no enum found: %s", name)
 }
 
+// LookupService 通过完整名称查找服务,例如 .foo.v1.BookService
+func (r *Registry) LookupService(name string) (*Service, error) {
+	if !strings.HasPrefix(name, ".") {
+		name = "." + name
+	}
+	s, ok := r._Services[name]
+	if !ok {
+		return nil, fmt.Errorf("no service found: %s", name)
+	}
+	return s, nil
+}
+
+// LookupMethod 通过完整名称查找RPC方法,例如 .foo.v1.BookService.GetBook
+func (r *Registry) LookupMethod(name string) (*Method, error) {
+	if !strings.HasPrefix(name, ".") {
+		name = "." + name
+	}
+	m, ok := r._Methods[name]
+	if !ok {
+		return nil, fmt.Errorf("no method found: %s", name)
+	}
+	return m, nil
+}
+
 // LookupFile 通过名字查找文件.
 func (r *Registry) LookupFile(name string) (*File, error) {
 	f, ok := r._Files[name]
@@ -208,6 +411,71 @@ func (r *Registry) SetImportPath(importPath string) {
 	r._ImportPath = importPath
 }
 
+// SetPathType 设置生成文件的输出路径模式
+func (r *Registry) SetPathType(pathType PathType) {
+	r._PathType = pathType
+}
+
+// SetMultiPackage 设置是否允许FileToGenerate跨越多个go包,需要在Load之前调用.
+// 开启后可以通过Packages按包获取目标文件.
+func (r *Registry) SetMultiPackage(multi bool) {
+	r._MultiPackage = multi
+}
+
+// SetModule 设置输出路径的模块前缀,对应protoc-gen-go的module参数
+func (r *Registry) SetModule(module string) {
+	r._Module = module
+}
+
+// ApplyParams 将插件参数应用到Registry上,需要在Load之前调用.
+// M参数对应AddPkgMap, import_prefix对应SetPrefix, import_path对应SetImportPath,
+// paths对应SetPathType, module对应SetModule, multi_package对应SetMultiPackage,
+// warnings_as_errors只检查取值是否合法.
+func (r *Registry) ApplyParams(p *Params) error {
+	r._Params = p
+	for file, pkg := range p.PkgMap {
+		r.AddPkgMap(file, pkg)
+	}
+	if prefix, ok := p.Get(ParamImportPrefix); ok {
+		r.SetPrefix(prefix)
+	}
+	if importPath, ok := p.Get(ParamImportPath); ok {
+		r.SetImportPath(importPath)
+	}
+	if paths, ok := p.Get(ParamPaths); ok {
+		pathType, err := ParsePathType(paths)
+		if err != nil {
+			return err
+		}
+		r.SetPathType(pathType)
+	}
+	if module, ok := p.Get(ParamModule); ok {
+		r.SetModule(module)
+	}
+	if _, err := p.Bool(ParamWarningsAsErrors); err != nil {
+		return err
+	}
+	if p.Has(ParamMultiPackage) {
+		multi, err := p.Bool(ParamMultiPackage)
+		if err != nil {
+			return err
+		}
+		r.SetMultiPackage(multi)
+	}
+	if r._Module != "" && r._PathType != PathTypeImport {
+		return fmt.Errorf("cannot use module=%s with paths=%s", r._Module, r._PathType)
+	}
+	return nil
+}
+
+// Params 返回通过ApplyParams设置的插件参数,没有设置时返回一个空参数集合
+func (r *Registry) Params() *Params {
+	if r._Params == nil {
+		r._Params, _ = ParseParams("")
+	}
+	return r._Params
+}
+
 // ReserveGoPackageAlias 用于检索,alias 对应的包路径是否存在
 func (r *Registry) ReserveGoPackageAlias(alias, pkgpath string) error {
 	if taken, ok := r._PkgAliases[alias]; ok {
@@ -303,18 +571,23 @@ func (r *Registry) _PackageIdentityName(f *descriptor.FileDescriptorProto) strin
 	return f.GetPackage()
 }
 
-// loadServices 从文件中解析服务
-func (r *Registry) _LoadServices(file *File) error {
+// _LoadServices 从文件中解析服务,解析失败的方法会记录到诊断信息中
+func (r *Registry) _LoadServices(file *File) {
 	var svcs []*Service
-	for _, sd := range file.GetService() {
+	for i, sd := range file.GetService() {
 		svc := &Service{
 			File:                   file,
 			ServiceDescriptorProto: sd,
+			_Path:                  []int32{_FileServiceTag, int32(i)},
 		}
-		for _, md := range sd.GetMethod() {
-			meth, err := r._NewMethod(svc, md)
+		for j, md := range sd.GetMethod() {
+			path := _AppendPath(svc._Path, _ServiceMethodTag, int32(j))
+			meth, err := r._NewMethod(svc, md, path)
 			if err != nil {
-				return err
+				if file.Generate {
+					r._Diags.Add(_AsDiagnostic(_Location{file, path, svc.FQSN() + "." + md.GetName()}, err))
+				}
+				continue
 			}
 			svc.Methods = append(svc.Methods, meth)
 		}
@@ -322,12 +595,16 @@ func (r *Registry) _LoadServices(file *File) error {
 			continue
 		}
 		svcs = append(svcs, svc)
+		r._Services[svc.FQSN()] = svc
+		for _, meth := range svc.Methods {
+			r._Methods[meth.FQMN()] = meth
+		}
 	}
 	file.Services = svcs
-	return nil
 }
 
-func (r *Registry) _NewMethod(svc *Service, md *descriptor.MethodDescriptorProto) (*Method, error) {
+// _NewMethod 创建RPC方法, path 是方法在SourceCodeInfo中的location path
+func (r *Registry) _NewMethod(svc *Service, md *descriptor.MethodDescriptorProto, path []int32) (*Method, error) {
 	requestType, err := r.LookupMsg(svc.File.GetPackage(), md.GetInputType())
 	if err != nil {
 		return nil, err
@@ -341,11 +618,75 @@ func (r *Registry) _NewMethod(svc *Service, md *descriptor.MethodDescriptorProto
 		MethodDescriptorProto: md,
 		RequestType:           requestType,
 		ResponseType:          responseType,
+		_Path:                 path,
 	}
 
+	// 依赖文件不生成代码,它们的HTTP映射有问题时只丢弃出错的映射,不报告诊断
+	report := svc.File.Generate
+	rule, err := _ExtractHTTPRule(md.GetOptions())
+	if err != nil {
+		if !report {
+			return meth, nil
+		}
+		return nil, fmt.Errorf("%s: %v", meth.FQMN(), err)
+	}
+	if rule == nil {
+		return meth, nil
+	}
+	rules := append([]*_HTTPRule{rule}, rule.AdditionalBindings...)
+	for i, rule := range rules {
+		if i > 0 && len(rule.AdditionalBindings) > 0 {
+			if !report {
+				continue
+			}
+			return nil, fmt.Errorf("%s: additional_binding cannot have nested additional_bindings", meth.FQMN())
+		}
+		b, err := r._NewBinding(meth, i, rule)
+		if err != nil {
+			if !report {
+				continue
+			}
+			return nil, err
+		}
+		meth.Bindings = append(meth.Bindings, b)
+	}
 	return meth, nil
 }
 
+// _NewBinding 根据 google.api.http 规则创建一个HTTP映射
+func (r *Registry) _NewBinding(meth *Method, index int, rule *_HTTPRule) (*Binding, error) {
+	if rule.HTTPMethod == "" || rule.Pattern == "" {
+		return nil, fmt.Errorf("%s: no HTTP method or path template in google.api.http", meth.FQMN())
+	}
+	tmpl, err := ParsePathTemplate(rule.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", meth.FQMN(), err)
+	}
+	b := &Binding{
+		Method:     meth,
+		Index:      index,
+		HTTPMethod: rule.HTTPMethod,
+		PathTmpl:   tmpl,
+	}
+	if rule.Body != "" && (rule.HTTPMethod == "GET" || rule.HTTPMethod == "DELETE") && meth.Service.File.Generate {
+		r.Warnf(meth, "%s binding %s declares body %q, which most HTTP clients do not send", rule.HTTPMethod, rule.Pattern, rule.Body)
+	}
+	for _, v := range tmpl.Fields() {
+		param, err := r._NewParam(meth, v)
+		if err != nil {
+			return nil, err
+		}
+		b.PathParams = append(b.PathParams, param)
+	}
+	if b.Body, err = r._NewBody(meth, rule.Body); err != nil {
+		return nil, err
+	}
+	if b.ResponseBody, err = r._NewResponse(meth, rule.ResponseBody); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
 // _NewParam RPC的参数
 func (r *Registry) _NewParam(meth *Method, path string) (Parameter, error) {
 	msg := meth.RequestType
@@ -358,17 +699,20 @@ func (r *Registry) _NewParam(meth *Method, path string) (Parameter, error) {
 		return Parameter{}, fmt.Errorf("invalid field access list for %s", path)
 	}
 	target := fields[l-1].Target
+	if target.IsMap() {
+		return Parameter{}, fmt.Errorf("map type %s in parameter of %s.%s: %s", target.GetTypeName(), meth.Service.GetName(), meth.GetName(), path)
+	}
 	switch target.GetType() {
 	case descriptor.FieldDescriptorProto_TYPE_MESSAGE, descriptor.FieldDescriptorProto_TYPE_GROUP:
-		if IsWellKnownType(*target.TypeName) {
-		} else {
+		if !r._Converters.IsWellKnownType(target.GetTypeName()) {
 			return Parameter{}, fmt.Errorf("aggregate type %s in parameter of %s.%s: %s", target.Type, meth.Service.GetName(), meth.GetName(), path)
 		}
 	}
 	return Parameter{
-		FieldPath: FieldPath(fields),
-		Method:    meth,
-		Target:    fields[l-1].Target,
+		FieldPath:  FieldPath(fields),
+		Method:     meth,
+		Target:     fields[l-1].Target,
+		Converters: r._Converters,
 	}, nil
 }
 
@@ -415,11 +759,10 @@ func (r *Registry) _ResolveFieldPath(msg *Message, path string, isPathParam bool
 			f := result[i-1].Target
 			switch f.GetType() {
 			case descriptor.FieldDescriptorProto_TYPE_MESSAGE, descriptor.FieldDescriptorProto_TYPE_GROUP:
-				var err error
-				msg, err = r.LookupMsg(msg.FQMN(), f.GetTypeName())
-				if err != nil {
-					return nil, err
+				if f.FieldMessage == nil {
+					return nil, fmt.Errorf("unresolved message type %s of field %s in %s", f.GetTypeName(), f.GetName(), path)
 				}
+				msg = f.FieldMessage
 			default:
 				return nil, fmt.Errorf("not an aggregate type: %s in %s", f.GetName(), path)
 			}
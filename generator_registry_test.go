@@ -0,0 +1,493 @@
+package gengo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	descriptor "github.com/yuansudong/gengo/descriptor"
+	plugin "github.com/yuansudong/gengo/plugin"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// _HTTPOption 返回带有 google.api.http 选项的MethodOptions, field是HttpRule中方法对应的字段编号
+func _HTTPOption(field protowire.Number, pattern, body string) *descriptor.MethodOptions {
+	var rule []byte
+	rule = protowire.AppendTag(rule, field, protowire.BytesType)
+	rule = protowire.AppendString(rule, pattern)
+	if body != "" {
+		rule = protowire.AppendTag(rule, _HTTPRuleBody, protowire.BytesType)
+		rule = protowire.AppendString(rule, body)
+	}
+	var opt []byte
+	opt = protowire.AppendTag(opt, _HTTPRuleFieldNumber, protowire.BytesType)
+	opt = protowire.AppendBytes(opt, rule)
+	opts := &descriptor.MethodOptions{}
+	opts.ProtoReflect().SetUnknown(opt)
+	return opts
+}
+
+// _ServiceFile 返回一个包含消息Req和服务Svc的文件, Svc的方法Call使用opts
+func _ServiceFile(name, pkg string, opts *descriptor.MethodOptions) *descriptor.FileDescriptorProto {
+	return &descriptor.FileDescriptorProto{
+		Name:    proto.String(name),
+		Package: proto.String(pkg),
+		Syntax:  proto.String("proto3"),
+		Options: &descriptor.FileOptions{GoPackage: proto.String("example.com/" + pkg)},
+		MessageType: []*descriptor.DescriptorProto{{
+			Name: proto.String("Req"),
+			Field: []*descriptor.FieldDescriptorProto{{
+				Name:   proto.String("name"),
+				Number: proto.Int32(1),
+				Type:   descriptor.FieldDescriptorProto_TYPE_STRING.Enum(),
+				Label:  descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			}},
+		}},
+		Service: []*descriptor.ServiceDescriptorProto{{
+			Name: proto.String("Svc"),
+			Method: []*descriptor.MethodDescriptorProto{{
+				Name:       proto.String("Call"),
+				InputType:  proto.String("." + pkg + ".Req"),
+				OutputType: proto.String("." + pkg + ".Req"),
+				Options:    opts,
+			}},
+		}},
+	}
+}
+
+func TestLoadDependencyBindings(t *testing.T) {
+	files := []*descriptor.FileDescriptorProto{
+		_ServiceFile("dep.proto", "dep", _HTTPOption(_HTTPRuleGet, "/v1/{nosuch}", "*")),
+		_ServiceFile("b.proto", "b", _HTTPOption(_HTTPRuleGet, "/v1/{name}", "")),
+	}
+
+	// 只生成b.proto时,dep.proto中的错误映射被丢弃,服务和方法依然可以查找
+	r := NewRegistry()
+	if err := r.Load(&plugin.CodeGeneratorRequest{FileToGenerate: []string{"b.proto"}, ProtoFile: files}); err != nil {
+		t.Fatalf("Load() failed because of a dependency: %v", err)
+	}
+	if len(r.Diagnostics()) != 0 {
+		t.Errorf("Load() reported diagnostics for a dependency: %v", r.Diagnostics())
+	}
+	if _, err := r.LookupService("dep.Svc"); err != nil {
+		t.Errorf("LookupService(dep.Svc) failed: %v", err)
+	}
+	meth, err := r.LookupMethod("dep.Svc.Call")
+	if err != nil {
+		t.Fatalf("LookupMethod(dep.Svc.Call) failed: %v", err)
+	}
+	if len(meth.Bindings) != 0 {
+		t.Errorf("dep.Svc.Call has %d bindings, want the invalid binding to be dropped", len(meth.Bindings))
+	}
+	meth, err = r.LookupMethod("b.Svc.Call")
+	if err != nil {
+		t.Fatalf("LookupMethod(b.Svc.Call) failed: %v", err)
+	}
+	if len(meth.Bindings) != 1 {
+		t.Errorf("b.Svc.Call has %d bindings, want 1", len(meth.Bindings))
+	}
+
+	// 生成dep.proto时报告错误
+	r = NewRegistry()
+	err = r.Load(&plugin.CodeGeneratorRequest{FileToGenerate: []string{"dep.proto"}, ProtoFile: files})
+	if err == nil || !strings.Contains(err.Error(), `no field "nosuch" found in Req`) {
+		t.Errorf("Load() = %v, want an error about the invalid binding", err)
+	}
+}
+
+func TestLoadDependencyWarnings(t *testing.T) {
+	files := []*descriptor.FileDescriptorProto{
+		_ServiceFile("dep.proto", "dep", _HTTPOption(_HTTPRuleDelete, "/v1/{name}", "*")),
+		_ServiceFile("b.proto", "b", _HTTPOption(_HTTPRuleGet, "/v1/{name}", "")),
+	}
+	for _, tc := range []struct {
+		target   string
+		warnings int
+	}{
+		{target: "b.proto", warnings: 0},
+		{target: "dep.proto", warnings: 1},
+	} {
+		r := NewRegistry()
+		if err := r.Load(&plugin.CodeGeneratorRequest{FileToGenerate: []string{tc.target}, ProtoFile: files}); err != nil {
+			t.Fatalf("Load(%s) failed: %v", tc.target, err)
+		}
+		if got := len(r.Diagnostics().Warnings()); got != tc.warnings {
+			t.Errorf("Load(%s) reported %d warnings, want %d: %v", tc.target, got, tc.warnings, r.Diagnostics())
+		}
+	}
+}
+
+func TestLoadMultiPackage(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		files []*descriptor.FileDescriptorProto
+		want  []string
+		err   string
+	}{
+		{
+			name: "grouped by import path",
+			files: []*descriptor.FileDescriptorProto{
+				_ImportFile("a.proto", "a", "example.com/x;x"),
+				_ImportFile("b.proto", "b", "example.com/y;y"),
+				_ImportFile("c.proto", "c", "example.com/x;x"),
+			},
+			want: []string{"example.com/x: a.proto c.proto", "example.com/y: b.proto"},
+		},
+		{
+			// 不同的go包可以使用相同的包名
+			name: "same name in different packages",
+			files: []*descriptor.FileDescriptorProto{
+				_ImportFile("a.proto", "a", "example.com/a/api;api"),
+				_ImportFile("b.proto", "b", "example.com/b/api;api"),
+			},
+			want: []string{"example.com/a/api: a.proto", "example.com/b/api: b.proto"},
+		},
+		{
+			name: "inconsistent names in one package",
+			files: []*descriptor.FileDescriptorProto{
+				_ImportFile("a.proto", "a", "example.com/x;x"),
+				_ImportFile("b.proto", "b", "example.com/y;y"),
+				_ImportFile("c.proto", "c", "example.com/x;z"),
+			},
+			err: "c.proto: inconsistent package names for example.com/x: x (a.proto) z",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &plugin.CodeGeneratorRequest{ProtoFile: tc.files}
+			for _, f := range tc.files {
+				req.FileToGenerate = append(req.FileToGenerate, f.GetName())
+			}
+			r := NewRegistry()
+			r.SetMultiPackage(true)
+			err := r.Load(req)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Errorf("Load() = %v, want error containing %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() failed: %v", err)
+			}
+			var got []string
+			for _, pf := range r.Packages() {
+				var names []string
+				for _, f := range pf.Files {
+					names = append(names, f.GetName())
+				}
+				got = append(got, pf.GoPkg.Path+": "+strings.Join(names, " "))
+			}
+			if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+				t.Errorf("Packages() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// _TypedField 返回一个类型为typ的字段, typeName为消息或枚举的名称
+func _TypedField(name string, number int32, label descriptor.FieldDescriptorProto_Label, typ descriptor.FieldDescriptorProto_Type, typeName string) *descriptor.FieldDescriptorProto {
+	f := &descriptor.FieldDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(number),
+		Label:  label.Enum(),
+		Type:   typ.Enum(),
+	}
+	if typeName != "" {
+		f.TypeName = proto.String(typeName)
+	}
+	return f
+}
+
+func TestLoadMapFields(t *testing.T) {
+	const (
+		optional = descriptor.FieldDescriptorProto_LABEL_OPTIONAL
+		repeated = descriptor.FieldDescriptorProto_LABEL_REPEATED
+		message  = descriptor.FieldDescriptorProto_TYPE_MESSAGE
+		str      = descriptor.FieldDescriptorProto_TYPE_STRING
+		int64t   = descriptor.FieldDescriptorProto_TYPE_INT64
+	)
+	entry := func(name string, value *descriptor.FieldDescriptorProto) *descriptor.DescriptorProto {
+		return &descriptor.DescriptorProto{
+			Name:    proto.String(name),
+			Field:   []*descriptor.FieldDescriptorProto{_TypedField("key", 1, optional, str, ""), value},
+			Options: &descriptor.MessageOptions{MapEntry: proto.Bool(true)},
+		}
+	}
+	file := &descriptor.FileDescriptorProto{
+		Name:    proto.String("map.proto"),
+		Package: proto.String("m"),
+		Syntax:  proto.String("proto3"),
+		Options: &descriptor.FileOptions{GoPackage: proto.String("example.com/m")},
+		MessageType: []*descriptor.DescriptorProto{
+			{Name: proto.String("Sub")},
+			{
+				// map<string, Sub> items = 1; map<string, int64> counts = 2;
+				// repeated Sub subs = 3; repeated Pair pairs = 4; Pair pair = 5;
+				Name: proto.String("Msg"),
+				Field: []*descriptor.FieldDescriptorProto{
+					_TypedField("items", 1, repeated, message, ".m.Msg.ItemsEntry"),
+					_TypedField("counts", 2, repeated, message, ".m.Msg.CountsEntry"),
+					_TypedField("subs", 3, repeated, message, ".m.Sub"),
+					_TypedField("pairs", 4, repeated, message, ".m.Msg.PairEntry"),
+					_TypedField("pair", 5, optional, message, ".m.Msg.PairEntry"),
+				},
+				NestedType: []*descriptor.DescriptorProto{
+					entry("ItemsEntry", _TypedField("value", 2, optional, message, ".m.Sub")),
+					entry("CountsEntry", _TypedField("value", 2, optional, int64t, "")),
+					// 名字以Entry结尾但没有设置map_entry的普通消息
+					{
+						Name: proto.String("PairEntry"),
+						Field: []*descriptor.FieldDescriptorProto{
+							_TypedField("key", 1, optional, str, ""),
+							_TypedField("value", 2, optional, str, ""),
+						},
+					},
+				},
+			},
+		},
+	}
+	r := NewRegistry()
+	if err := r.Load(&plugin.CodeGeneratorRequest{FileToGenerate: []string{"map.proto"}, ProtoFile: []*descriptor.FileDescriptorProto{file}}); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	for fqmn, want := range map[string]bool{".m.Msg.ItemsEntry": true, ".m.Msg.CountsEntry": true, ".m.Msg.PairEntry": false, ".m.Msg": false} {
+		m, err := r.LookupMsg("", fqmn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.IsMapEntry() != want {
+			t.Errorf("%s.IsMapEntry() = %v, want %v", fqmn, m.IsMapEntry(), want)
+		}
+	}
+
+	m, err := r.LookupMsg("", ".m.Msg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		field string
+		isMap bool
+		entry string
+		value descriptor.FieldDescriptorProto_Type
+		// valueMsg map的值为消息时,值字段解析出的消息
+		valueMsg string
+	}{
+		{field: "items", isMap: true, entry: ".m.Msg.ItemsEntry", value: message, valueMsg: ".m.Sub"},
+		{field: "counts", isMap: true, entry: ".m.Msg.CountsEntry", value: int64t},
+		{field: "subs", entry: ".m.Sub"},
+		{field: "pairs", entry: ".m.Msg.PairEntry"},
+		{field: "pair", entry: ".m.Msg.PairEntry"},
+	} {
+		f := m.LookupField(tc.field)
+		if f.IsMap() != tc.isMap {
+			t.Errorf("%s.IsMap() = %v, want %v", tc.field, f.IsMap(), tc.isMap)
+		}
+		if f.FieldMessage == nil || f.FieldMessage.FQMN() != tc.entry {
+			t.Errorf("%s.FieldMessage = %v, want %s", tc.field, f.FieldMessage, tc.entry)
+		}
+		if !tc.isMap {
+			if f.MapKey != nil || f.MapValue != nil {
+				t.Errorf("%s has map key or value", tc.field)
+			}
+			continue
+		}
+		if f.MapKey.GetName() != "key" || f.MapKey.GetType() != str || f.MapKey.Message != f.FieldMessage {
+			t.Errorf("%s.MapKey = %v, want the key field of %s", tc.field, f.MapKey, tc.entry)
+		}
+		if f.MapValue.GetName() != "value" || f.MapValue.GetType() != tc.value || f.MapValue.Message != f.FieldMessage {
+			t.Errorf("%s.MapValue = %v, want the value field of %s", tc.field, f.MapValue, tc.entry)
+		}
+		if tc.valueMsg != "" && (f.MapValue.FieldMessage == nil || f.MapValue.FieldMessage.FQMN() != tc.valueMsg) {
+			t.Errorf("%s.MapValue.FieldMessage = %v, want %s", tc.field, f.MapValue.FieldMessage, tc.valueMsg)
+		}
+	}
+}
+
+func TestLoadResolvesFieldTypes(t *testing.T) {
+	const (
+		optional = descriptor.FieldDescriptorProto_LABEL_OPTIONAL
+		message  = descriptor.FieldDescriptorProto_TYPE_MESSAGE
+		group    = descriptor.FieldDescriptorProto_TYPE_GROUP
+		enum     = descriptor.FieldDescriptorProto_TYPE_ENUM
+		str      = descriptor.FieldDescriptorProto_TYPE_STRING
+	)
+	kind := func(name string) *descriptor.EnumDescriptorProto {
+		return &descriptor.EnumDescriptorProto{
+			Name:  proto.String(name),
+			Value: []*descriptor.EnumValueDescriptorProto{{Name: proto.String(strings.ToUpper(name) + "_UNSPECIFIED"), Number: proto.Int32(0)}},
+		}
+	}
+	dep := &descriptor.FileDescriptorProto{
+		Name:    proto.String("dep.proto"),
+		Package: proto.String("dep"),
+		Syntax:  proto.String("proto2"),
+		Options: &descriptor.FileOptions{GoPackage: proto.String("example.com/dep")},
+		MessageType: []*descriptor.DescriptorProto{{
+			Name:       proto.String("Msg"),
+			NestedType: []*descriptor.DescriptorProto{{Name: proto.String("Inner")}},
+			EnumType:   []*descriptor.EnumDescriptorProto{kind("State")},
+		}},
+		EnumType: []*descriptor.EnumDescriptorProto{kind("Kind")},
+	}
+	holder := func(fields ...*descriptor.FieldDescriptorProto) *descriptor.FileDescriptorProto {
+		return &descriptor.FileDescriptorProto{
+			Name:       proto.String("b.proto"),
+			Package:    proto.String("b.sub"),
+			Syntax:     proto.String("proto2"),
+			Dependency: []string{"dep.proto"},
+			Options:    &descriptor.FileOptions{GoPackage: proto.String("example.com/b")},
+			MessageType: []*descriptor.DescriptorProto{
+				{Name: proto.String("Local")},
+				{
+					Name:       proto.String("Holder"),
+					Field:      fields,
+					NestedType: []*descriptor.DescriptorProto{{Name: proto.String("Group")}},
+				},
+			},
+		}
+	}
+
+	r := NewRegistry()
+	if err := r.Load(&plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"b.proto"},
+		ProtoFile: []*descriptor.FileDescriptorProto{dep, holder(
+			_TypedField("msg", 1, optional, message, ".dep.Msg"),
+			_TypedField("inner", 2, optional, message, ".dep.Msg.Inner"),
+			_TypedField("kind", 3, optional, enum, ".dep.Kind"),
+			_TypedField("state", 4, optional, enum, ".dep.Msg.State"),
+			// 相对名称从字段所在的消息开始逐级向外查找
+			_TypedField("local", 5, optional, message, "Local"),
+			_TypedField("group", 6, optional, group, ".b.sub.Holder.Group"),
+			_TypedField("name", 7, optional, str, ""),
+		)},
+	}); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	m, err := r.LookupMsg("", ".b.sub.Holder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		field, msg, enum string
+	}{
+		{field: "msg", msg: ".dep.Msg"},
+		{field: "inner", msg: ".dep.Msg.Inner"},
+		{field: "kind", enum: ".dep.Kind"},
+		{field: "state", enum: ".dep.Msg.State"},
+		{field: "local", msg: ".b.sub.Local"},
+		{field: "group", msg: ".b.sub.Holder.Group"},
+		{field: "name"},
+	} {
+		f := m.LookupField(tc.field)
+		var msg, enum string
+		if f.FieldMessage != nil {
+			msg = f.FieldMessage.FQMN()
+		}
+		if f.FieldEnum != nil {
+			enum = f.FieldEnum.FQEN()
+		}
+		if msg != tc.msg || enum != tc.enum {
+			t.Errorf("%s: FieldMessage %q FieldEnum %q, want %q %q", tc.field, msg, enum, tc.msg, tc.enum)
+		}
+	}
+	if f := m.LookupField("kind"); f.FieldEnum.File.GoPkg.Path != "example.com/dep" {
+		t.Errorf("kind resolved to an enum in %s", f.FieldEnum.File.GoPkg.Path)
+	}
+
+	// 无法解析的类型名作为字段的诊断信息返回,所有错误一起报告
+	r = NewRegistry()
+	err = r.Load(&plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"b.proto"},
+		ProtoFile: []*descriptor.FileDescriptorProto{dep, holder(
+			_TypedField("msg", 1, optional, message, ".dep.Missing"),
+			_TypedField("kind", 2, optional, enum, ".dep.Msg"),
+		)},
+	})
+	if err == nil {
+		t.Fatal("Load() succeeded with unresolved field types")
+	}
+	var got []string
+	for _, d := range r.Diagnostics() {
+		got = append(got, d.Element+": "+d.Message)
+	}
+	want := []string{".b.sub.Holder.msg: no message found: .dep.Missing", ".b.sub.Holder.kind: no enum found: .dep.Msg"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diagnostics() = %q, want %q", got, want)
+	}
+}
+
+func TestLoadEnumAliases(t *testing.T) {
+	// enum Color { RED = 0; CRIMSON = 0; BLUE = 1; SCARLET = 0; NAVY = 1; }
+	colorFile := func(allowAlias bool) *descriptor.FileDescriptorProto {
+		value := func(name string, number int32) *descriptor.EnumValueDescriptorProto {
+			return &descriptor.EnumValueDescriptorProto{Name: proto.String(name), Number: proto.Int32(number)}
+		}
+		e := &descriptor.EnumDescriptorProto{
+			Name:  proto.String("Color"),
+			Value: []*descriptor.EnumValueDescriptorProto{value("RED", 0), value("CRIMSON", 0), value("BLUE", 1), value("SCARLET", 0), value("NAVY", 1)},
+		}
+		if allowAlias {
+			e.Options = &descriptor.EnumOptions{AllowAlias: proto.Bool(true)}
+		}
+		return &descriptor.FileDescriptorProto{
+			Name:     proto.String("color.proto"),
+			Package:  proto.String("color"),
+			Syntax:   proto.String("proto3"),
+			Options:  &descriptor.FileOptions{GoPackage: proto.String("example.com/color")},
+			EnumType: []*descriptor.EnumDescriptorProto{e},
+			SourceCodeInfo: &descriptor.SourceCodeInfo{Location: []*descriptor.SourceCodeInfo_Location{
+				_SpanLoc([]int32{3, 2, 14}, 5, 0, 2, 1),
+			}},
+		}
+	}
+	aliases := map[string]string{"RED": "", "CRIMSON": "RED", "BLUE": "", "SCARLET": "RED", "NAVY": "BLUE"}
+
+	r := NewRegistry()
+	if err := r.Load(&plugin.CodeGeneratorRequest{FileToGenerate: []string{"color.proto"}, ProtoFile: []*descriptor.FileDescriptorProto{colorFile(true)}}); err != nil {
+		t.Fatalf("Load() with allow_alias failed: %v", err)
+	}
+	if len(r.Diagnostics()) != 0 {
+		t.Errorf("Load() with allow_alias reported %v", r.Diagnostics())
+	}
+	e, err := r.LookupEnum("", ".color.Color")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range e.Values {
+		var aliasOf string
+		if v.AliasOf != nil {
+			aliasOf = v.AliasOf.GetName()
+		}
+		if aliasOf != aliases[v.GetName()] || v.IsAlias() != (aliasOf != "") {
+			t.Errorf("%s: AliasOf %q IsAlias %v, want %q", v.GetName(), aliasOf, v.IsAlias(), aliases[v.GetName()])
+		}
+	}
+	// 按编号查找时返回第一个使用这个编号的值,按名称查找可以找到别名
+	if v := e.LookupValueByNumber(0); v.GetName() != "RED" {
+		t.Errorf("LookupValueByNumber(0) = %s, want RED", v.GetName())
+	}
+	if v := e.LookupValueByNumber(1); v.GetName() != "BLUE" {
+		t.Errorf("LookupValueByNumber(1) = %s, want BLUE", v.GetName())
+	}
+	if v := e.LookupValue("CRIMSON"); v == nil || v.AliasOf != e.LookupValue("RED") || v.GoName() != "Color_CRIMSON" {
+		t.Errorf("LookupValue(CRIMSON) = %v, want an alias of RED named Color_CRIMSON", v)
+	}
+
+	// 没有设置allow_alias时每个重复的编号都报告一条带位置的错误
+	r = NewRegistry()
+	err = r.Load(&plugin.CodeGeneratorRequest{FileToGenerate: []string{"color.proto"}, ProtoFile: []*descriptor.FileDescriptorProto{colorFile(false)}})
+	want := "color.proto:4:3: CRIMSON uses the same number 0 as RED, but allow_alias is not set\n" +
+		"color.proto: SCARLET uses the same number 0 as RED, but allow_alias is not set\n" +
+		"color.proto: NAVY uses the same number 1 as BLUE, but allow_alias is not set"
+	if err == nil || err.Error() != want {
+		t.Errorf("Load() without allow_alias = %v, want\n%s", err, want)
+	}
+	var elems []string
+	for _, d := range r.Diagnostics() {
+		elems = append(elems, d.Element)
+	}
+	if want := []string{".color.CRIMSON", ".color.SCARLET", ".color.NAVY"}; !reflect.DeepEqual(elems, want) {
+		t.Errorf("diagnostic elements = %q, want %q", elems, want)
+	}
+}
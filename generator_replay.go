@@ -0,0 +1,203 @@
+package gengo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	plugin "github.com/yuansudong/gengo/plugin"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// EnvDumpRequest 设置后,GetRequest会把收到的请求保存到这个环境变量指定的路径
+	EnvDumpRequest = "GENGO_DUMP_REQUEST"
+	// ParamDumpRequest 与EnvDumpRequest作用相同的插件参数
+	ParamDumpRequest = "dump_request"
+)
+
+// Generator 是基于gengo的代码生成函数,reg是已经加载完成的Registry,
+// 插件参数可以通过reg.Params获取
+type Generator func(reg *Registry) ([]*plugin.CodeGeneratorResponse_File, error)
+
+// Generate 用gen处理一个请求,生成过程中的错误会写入响应的Error字段,
+// 通过Registry.Warnf报告的警告会输出到WarningWriter
+func Generate(req *plugin.CodeGeneratorRequest, gen Generator) *plugin.CodeGeneratorResponse {
+	files, err := _Generate(req, gen)
+	if err != nil {
+		return &plugin.CodeGeneratorResponse{Error: proto.String(err.Error())}
+	}
+	return &plugin.CodeGeneratorResponse{File: files}
+}
+
+// _Generate 解析插件参数,加载Registry并调用gen
+func _Generate(req *plugin.CodeGeneratorRequest, gen Generator) ([]*plugin.CodeGeneratorResponse_File, error) {
+	params, err := ParseParams(req.GetParameter())
+	if err != nil {
+		return nil, err
+	}
+	reg := NewRegistry()
+	if err := reg.ApplyParams(params); err != nil {
+		return nil, err
+	}
+	var files []*plugin.CodeGeneratorResponse_File
+	err = reg.Load(req)
+	if err == nil {
+		files, err = gen(reg)
+	}
+	promoted := _ReportWarnings(reg)
+	if len(promoted) == 0 {
+		return files, err
+	}
+	switch diags := err.(type) {
+	case nil:
+		return nil, promoted
+	case Diagnostics:
+		return nil, append(diags, promoted...)
+	case *Diagnostic:
+		return nil, append(Diagnostics{diags}, promoted...)
+	}
+	// 其他错误没有位置信息,作为不带文件名的诊断与提升的警告一起返回
+	return nil, append(Diagnostics{{Severity: SeverityError, Message: err.Error()}}, promoted...)
+}
+
+// _DumpPath 返回保存请求的路径,环境变量优先于插件参数
+func _DumpPath(req *plugin.CodeGeneratorRequest) string {
+	if p := os.Getenv(EnvDumpRequest); p != "" {
+		return p
+	}
+	params, err := ParseParams(req.GetParameter())
+	if err != nil {
+		return ""
+	}
+	p, _ := params.Get(ParamDumpRequest)
+	return p
+}
+
+// DumpRequest 将请求以二进制格式保存到path,同时以JSON格式保存到path.json
+func DumpRequest(req *plugin.CodeGeneratorRequest, path string) error {
+	bin, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, bin, 0644); err != nil {
+		return err
+	}
+	js, err := protojson.MarshalOptions{Multiline: true}.Marshal(req)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path+".json", js, 0644)
+}
+
+// ReadRequestFile 读取DumpRequest保存的请求,以 .json 结尾的文件按JSON格式解析
+func ReadRequestFile(path string) (*plugin.CodeGeneratorRequest, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	req := new(plugin.CodeGeneratorRequest)
+	if strings.HasSuffix(path, ".json") {
+		err = protojson.Unmarshal(buf, req)
+	} else {
+		err = proto.Unmarshal(buf, req)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse request %s: %v", path, err)
+	}
+	return req, nil
+}
+
+// StripDumpRequest 从请求的插件参数中删除dump_request,
+// 重放保存下来的请求时避免插件再次保存,覆盖原来的文件
+func StripDumpRequest(req *plugin.CodeGeneratorRequest) {
+	if req.Parameter == nil {
+		return
+	}
+	var kept []string
+	for _, item := range strings.Split(req.GetParameter(), ",") {
+		key := item
+		if i := strings.IndexByte(item, '='); i >= 0 {
+			key = item[:i]
+		}
+		if strings.TrimSpace(key) != ParamDumpRequest {
+			kept = append(kept, item)
+		}
+	}
+	req.Parameter = proto.String(strings.Join(kept, ","))
+}
+
+// CheckOutputName 像protoc一样检查响应中的文件名:
+// 必须是以 / 分隔的相对路径,并且清理之后不能以 .. 跳出输出目录
+func CheckOutputName(name string) error {
+	if name == "" {
+		return fmt.Errorf("empty file name")
+	}
+	if path.IsAbs(name) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return fmt.Errorf("%s: file name must be relative", name)
+	}
+	clean := path.Clean(name)
+	if clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("%s: file name must not contain .. or refer to the output directory itself", name)
+	}
+	return nil
+}
+
+// WriteResponseFiles 像protoc一样将响应中的文件写入dir目录.
+// 没有文件名的条目会追加到上一个文件的末尾,不支持插入点.
+// 绝对路径以及会跳出dir的文件名会返回错误,此时不会写入任何文件.
+func WriteResponseFiles(dir string, rsp *plugin.CodeGeneratorResponse) error {
+	if rsp.Error != nil {
+		return fmt.Errorf("generator error: %s", rsp.GetError())
+	}
+	var names []string
+	contents := make(map[string]*strings.Builder)
+	var last string
+	for _, f := range rsp.GetFile() {
+		if f.GetInsertionPoint() != "" {
+			return fmt.Errorf("%s: insertion points are not supported", f.GetName())
+		}
+		name := f.GetName()
+		if name == "" {
+			if last == "" {
+				return fmt.Errorf("first file in response has no name")
+			}
+			name = last
+		} else if err := CheckOutputName(name); err != nil {
+			return err
+		}
+		b, ok := contents[name]
+		if !ok {
+			b = new(strings.Builder)
+			contents[name] = b
+			names = append(names, name)
+		}
+		b.WriteString(f.GetContent())
+		last = name
+	}
+	for _, name := range names {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(p, []byte(contents[name].String()), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Replay 用gen处理path中保存的请求,并将生成的文件写入outDir,
+// 用于在没有protoc环境的情况下复现问题. 请求中的dump_request参数会被删除.
+func Replay(path string, gen Generator, outDir string) error {
+	req, err := ReadRequestFile(path)
+	if err != nil {
+		return err
+	}
+	StripDumpRequest(req)
+	return WriteResponseFiles(outDir, Generate(req, gen))
+}
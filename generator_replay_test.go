@@ -0,0 +1,137 @@
+package gengo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	descriptor "github.com/yuansudong/gengo/descriptor"
+	plugin "github.com/yuansudong/gengo/plugin"
+	"google.golang.org/protobuf/proto"
+)
+
+// _TempDir 创建一个测试结束时删除的临时目录
+func _TempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "gengo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestCheckOutputName(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		ok   bool
+	}{
+		{name: "a.go", ok: true},
+		{name: "foo/a.go", ok: true},
+		{name: "foo/../a.go", ok: true},
+		{name: "./foo/a.go", ok: true},
+		{name: "", ok: false},
+		{name: "/etc/passwd", ok: false},
+		{name: "../a.go", ok: false},
+		{name: "../../x", ok: false},
+		{name: "foo/../../a.go", ok: false},
+		{name: "..", ok: false},
+		{name: "foo/..", ok: false},
+	} {
+		if err := CheckOutputName(tc.name); (err == nil) != tc.ok {
+			t.Errorf("CheckOutputName(%q) = %v, want ok %v", tc.name, err, tc.ok)
+		}
+	}
+}
+
+func TestWriteResponseFiles(t *testing.T) {
+	dir := _TempDir(t)
+	rsp := &plugin.CodeGeneratorResponse{File: []*plugin.CodeGeneratorResponse_File{
+		{Name: proto.String("foo/a.go"), Content: proto.String("package foo\n")},
+		// 没有文件名的条目追加到上一个文件
+		{Content: proto.String("// more\n")},
+		{Name: proto.String("b.txt"), Content: proto.String("b")},
+	}}
+	if err := WriteResponseFiles(dir, rsp); err != nil {
+		t.Fatalf("WriteResponseFiles() failed: %v", err)
+	}
+	for name, want := range map[string]string{"foo/a.go": "package foo\n// more\n", "b.txt": "b"} {
+		got, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", name, got, err, want)
+		}
+	}
+}
+
+func TestWriteResponseFilesRejectsEscapes(t *testing.T) {
+	for _, name := range []string{"../../x", "/tmp/x", "a/../../x"} {
+		root := _TempDir(t)
+		out := filepath.Join(root, "out")
+		rsp := &plugin.CodeGeneratorResponse{File: []*plugin.CodeGeneratorResponse_File{
+			{Name: proto.String("ok.go"), Content: proto.String("ok")},
+			{Name: proto.String(name), Content: proto.String("x")},
+		}}
+		if err := WriteResponseFiles(out, rsp); err == nil {
+			t.Errorf("WriteResponseFiles(%q) succeeded, want error", name)
+		}
+		// 文件名不合法时不写入任何文件
+		if _, err := os.Stat(out); !os.IsNotExist(err) {
+			t.Errorf("WriteResponseFiles(%q) created the output directory", name)
+		}
+	}
+}
+
+func TestStripDumpRequest(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want string
+	}{
+		{in: "dump_request=/tmp/req.bin", want: ""},
+		{in: "paths=source_relative,dump_request=/tmp/req.bin,Mfoo.proto=example.com/foo", want: "paths=source_relative,Mfoo.proto=example.com/foo"},
+		{in: "paths=import, dump_request", want: "paths=import"},
+		{in: "paths=import", want: "paths=import"},
+	} {
+		req := &plugin.CodeGeneratorRequest{Parameter: proto.String(tc.in)}
+		StripDumpRequest(req)
+		if got := req.GetParameter(); got != tc.want {
+			t.Errorf("StripDumpRequest(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+	req := new(plugin.CodeGeneratorRequest)
+	if StripDumpRequest(req); req.Parameter != nil {
+		t.Errorf("StripDumpRequest() set a parameter on a request without one")
+	}
+}
+
+func TestReplay(t *testing.T) {
+	dir := _TempDir(t)
+	path := filepath.Join(dir, "req.bin")
+	req := &plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"foo/a.proto"},
+		Parameter:      proto.String("dump_request=" + path),
+		ProtoFile: []*descriptor.FileDescriptorProto{{
+			Name:    proto.String("foo/a.proto"),
+			Package: proto.String("foo"),
+			Options: &descriptor.FileOptions{GoPackage: proto.String("example.com/foo")},
+		}},
+	}
+	if err := DumpRequest(req, path); err != nil {
+		t.Fatal(err)
+	}
+	var params []string
+	gen := func(reg *Registry) ([]*plugin.CodeGeneratorResponse_File, error) {
+		params = reg.Params().Keys()
+		return []*plugin.CodeGeneratorResponse_File{{Name: proto.String("a.txt"), Content: proto.String("a")}}, nil
+	}
+	out := filepath.Join(dir, "out")
+	if err := Replay(path, gen, out); err != nil {
+		t.Fatalf("Replay() failed: %v", err)
+	}
+	if len(params) != 0 {
+		t.Errorf("Replay() passed parameters %q to the generator, want dump_request stripped", params)
+	}
+	if got, err := ioutil.ReadFile(filepath.Join(out, "a.txt")); err != nil || string(got) != "a" {
+		t.Errorf("a.txt = %q, %v; want %q", got, err, "a")
+	}
+}
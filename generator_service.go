@@ -7,7 +7,7 @@ import (
 	"github.com/yuansudong/gengo/descriptor"
 )
 
-// IsWellKnownType 用于判断是否是wellknown类型
+// IsWellKnownType 用于判断是否是默认转换函数集合支持的wellknown类型
 func IsWellKnownType(typeName string) bool {
 	_, ok := _WellKnownTypeConv[typeName]
 	return ok
@@ -20,6 +20,8 @@ type Service struct {
 	*descriptor.ServiceDescriptorProto
 	// Methods 该服务下有哪些RPC的方法
 	Methods []*Method
+	// _Path 服务在SourceCodeInfo中的location path
+	_Path []int32
 }
 
 // FQSN 返回service的完整文件名
@@ -41,6 +43,10 @@ type Method struct {
 	RequestType *Message
 	// ResponseType RPC方法的响应类型
 	ResponseType *Message
+	// Bindings 由 google.api.http 声明的HTTP映射
+	Bindings []*Binding
+	// _Path 方法在SourceCodeInfo中的location path
+	_Path []int32
 }
 
 // FQMN 返回RPC的方法名
@@ -55,9 +61,39 @@ func (m *Method) FQMN() string {
 type Field struct {
 	// Message 这个字段属于哪个消息
 	Message *Message
-	// FieldMessage 字段的消息类型.
+	// FieldMessage 字段的消息类型,由Registry.Load解析.
 	FieldMessage *Message
+	// FieldEnum 字段的枚举类型,由Registry.Load解析.
+	FieldEnum *Enum
+	// Oneof 字段所属的oneof,不属于真正的oneof时为nil
+	Oneof *Oneof
+	// MapKey map字段的键,不是map字段时为nil
+	MapKey *Field
+	// MapValue map字段的值,不是map字段时为nil
+	MapValue *Field
 	*descriptor.FieldDescriptorProto
+	// _Path 字段在SourceCodeInfo中的location path
+	_Path []int32
+	// _GoName 字段在Go结构体中的字段名
+	_GoName string
+	// _WrapperName oneof成员字段的包装结构体名
+	_WrapperName string
+}
+
+// IsMap 判断字段是否是map<K,V>类型
+func (f *Field) IsMap() bool {
+	return f.MapKey != nil && f.MapValue != nil
+}
+
+// IsProto3Optional 判断字段是否是proto3中用optional声明的字段.
+// 这种字段属于一个合成的oneof,但生成的Go代码中是一个指针字段.
+func (f *Field) IsProto3Optional() bool {
+	return f.GetProto3Optional()
+}
+
+// InRealOneof 判断字段是否属于一个真正的oneof
+func (f *Field) InRealOneof() bool {
+	return f.Oneof != nil
 }
 
 // Parameter 在RPC里的参数
@@ -68,27 +104,43 @@ type Parameter struct {
 	Target *Field
 	// Method 这个参数属于RPC中的哪个方法
 	Method *Method
+	// Converters 参数使用的转换函数集合,为nil时使用DefaultConverterSet
+	Converters *ConverterSet
+}
+
+// ConvertFuncExpr 返回把路径参数转换为字段值的函数表达式以及该表达式需要导入的包,
+// 表达式使用包名限定,例如 runtime.Int64. 转换函数集合的包没有设置Name时,
+// 包名由PackageNameFromPath推断,返回的GoPackage中会带上推断出的Name.
+// 生成文件中包名可能被重命名时使用ConvertFuncExprIn.
+func (p Parameter) ConvertFuncExpr() (string, GoPackage, error) {
+	set := p._ConverterSet()
+	conv, err := set._Func(p)
+	if err != nil {
+		return "", GoPackage{}, err
+	}
+	pkg := set.Package
+	if pkg.Name == "" {
+		pkg.Name = PackageNameFromPath(pkg.Path)
+	}
+	return pkg.Name + "." + conv, pkg, nil
 }
 
-// ConvertFuncExpr 获得转换函数
-func (p Parameter) ConvertFuncExpr() (string, error) {
-	tbl := _Proto3ConvertFuncs
-	if !p.IsProto2() && p.IsRepeated() {
-		tbl = _Proto3RepeatedConvertFuncs
-	} else if p.IsProto2() && !p.IsRepeated() {
-		tbl = _Proto2ConvertFuncs
-	} else if p.IsProto2() && p.IsRepeated() {
-		tbl = _Proto2RepeatedConvertFuncs
-	}
-	typ := p.Target.GetType()
-	conv, ok := tbl[typ]
-	if !ok {
-		conv, ok = _WellKnownTypeConv[p.Target.GetTypeName()]
+// ConvertFuncExprIn 返回在im所属的生成文件中引用转换函数的表达式,并记录导入
+func (p Parameter) ConvertFuncExprIn(im *ImportManager) (string, error) {
+	set := p._ConverterSet()
+	conv, err := set._Func(p)
+	if err != nil {
+		return "", err
 	}
-	if !ok {
-		return "", fmt.Errorf("unsupported field type %s of parameter %s in %s.%s", typ, p.FieldPath, p.Method.Service.GetName(), p.Method.GetName())
+	return im.Qualify(set.Package, conv), nil
+}
+
+// _ConverterSet 返回参数使用的转换函数集合
+func (p Parameter) _ConverterSet() *ConverterSet {
+	if p.Converters == nil {
+		return DefaultConverterSet()
 	}
-	return conv, nil
+	return p.Converters
 }
 
 // IsEnum 判断参数是否是枚举
@@ -101,6 +153,11 @@ func (p Parameter) IsRepeated() bool {
 	return p.Target.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED
 }
 
+// IsProto3Optional 判断参数是否是proto3 optional字段
+func (p Parameter) IsProto3Optional() bool {
+	return p.Target.IsProto3Optional()
+}
+
 // IsProto2 返回是不是protobuf2协议
 func (p Parameter) IsProto2() bool {
 	return p.Target.Message.File.proto2()
@@ -147,11 +204,9 @@ func (p FieldPath) AssignableExpr(msgExpr string) string {
 	var preparations []string
 	components := msgExpr
 	for i, c := range p {
-		if c.Target.OneofIndex != nil {
-			index := c.Target.OneofIndex
-			msg := c.Target.Message
-			oneOfName := Camel(msg.GetOneofDecl()[*index].GetName())
-			oneofFieldName := msg.GetName() + "_" + c.AssignableExpr()
+		if c.Target.InRealOneof() {
+			oneOfName := c.Target.Oneof.GoName()
+			oneofFieldName := c.Target.OneofWrapperName()
 			components = components + "." + oneOfName
 			s := `if %s == nil {
 				%s =&%s{}
@@ -183,13 +238,13 @@ type FieldPathComponent struct {
 
 // AssignableExpr 返回一个不可忽视的表达式,没有v3与v2的差别.
 func (c FieldPathComponent) AssignableExpr() string {
-	return Camel(c.Name)
+	return c.Target.GoName()
 }
 
 // ValueExpr 为一个字段返回一个表达式.
 func (c FieldPathComponent) ValueExpr() string {
 	if c.Target.Message.File.proto2() {
-		return fmt.Sprintf("Get%s()", Camel(c.Name))
+		return c.Target.GetterName() + "()"
 	}
-	return Camel(c.Name)
+	return c.Target.GoName()
 }
@@ -0,0 +1,143 @@
+package gengo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/yuansudong/gengo/casing"
+)
+
+// ParamTemplates 指定用户模板目录的插件参数
+const ParamTemplates = "templates"
+
+// _TemplateExt 模板文件的扩展名
+const _TemplateExt = ".tmpl"
+
+// _ErrNoGeneratedFile 模板没有通过ExecuteTemplate执行时,依赖生成文件的函数返回的错误
+var _ErrNoGeneratedFile = errors.New("no generated file bound to template, execute it with gengo.ExecuteTemplate")
+
+// FuncMap 返回模板中可以使用的函数,g是模板输出的生成文件,
+// GoType, Import 和 Qualify 会在g的导入管理器中记录用到的包.
+// g为nil时这三个函数返回错误,模板执行会因此失败.
+//
+//	Camel      下划线命名转为驼峰命名
+//	NameUpper  下划线命名转为首字母大写的驼峰命名
+//	LowerCamel, Title, Snake, ScreamingSnake, Kebab, JSONName
+//	           casing包中对应的命名转换
+//	GoType     *Message 或 *Enum 在生成文件中的Go类型
+//	FQMN       消息,枚举,服务,方法的完整名称
+//	Comments   将元素的proto注释渲染为Go注释
+//	Import     导入一个包并返回本地包名
+//	Qualify    导入一个包并返回 包名.标识符
+func FuncMap(g *GeneratedFile) template.FuncMap {
+	return template.FuncMap{
+		"Camel":          Camel,
+		"NameUpper":      NameUpper,
+		"LowerCamel":     casing.LowerCamel,
+		"Title":          casing.Title,
+		"Snake":          casing.Snake,
+		"ScreamingSnake": casing.ScreamingSnake,
+		"Kebab":          casing.Kebab,
+		"JSONName":       casing.JSONName,
+		"GoType": func(v interface{}) (string, error) {
+			if g == nil {
+				return "", _ErrNoGeneratedFile
+			}
+			switch t := v.(type) {
+			case *Message:
+				return g.MessageType(t), nil
+			case *Enum:
+				return g.EnumType(t), nil
+			}
+			return "", fmt.Errorf("GoType: unsupported type %T", v)
+		},
+		"FQMN": func(v interface{}) (string, error) {
+			switch t := v.(type) {
+			case *Message:
+				return t.FQMN(), nil
+			case *Enum:
+				return t.FQEN(), nil
+			case *Service:
+				return t.FQSN(), nil
+			case *Method:
+				return t.FQMN(), nil
+			}
+			return "", fmt.Errorf("FQMN: unsupported type %T", v)
+		},
+		"Comments": func(v interface{}) (string, error) {
+			switch t := v.(type) {
+			case Comments:
+				return t.GoComment(), nil
+			case interface{ Comments() Comments }:
+				return t.Comments().GoComment(), nil
+			}
+			return "", fmt.Errorf("Comments: unsupported type %T", v)
+		},
+		"Import": func(importPath string) (string, error) {
+			if g == nil {
+				return "", _ErrNoGeneratedFile
+			}
+			return g.Import(GoPackage{Path: importPath, Name: PackageNameFromPath(importPath)}), nil
+		},
+		"Qualify": func(importPath, ident string) (string, error) {
+			if g == nil {
+				return "", _ErrNoGeneratedFile
+			}
+			return g.QualifiedGoIdent(GoPackage{Path: importPath, Name: PackageNameFromPath(importPath)}, ident), nil
+		},
+	}
+}
+
+// GoComment 将前置注释渲染为Go的 // 注释,没有前置注释时返回空字符串
+func (c Comments) GoComment() string {
+	text := strings.TrimSuffix(c.Leading, "\n")
+	if text == "" {
+		return ""
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("//"+line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// LoadTemplates 加载dir目录下所有 .tmpl 文件,模板名是不带目录的文件名.
+// 返回的模板需要通过ExecuteTemplate执行,直接执行时 GoType, Import 和 Qualify 会返回错误.
+func LoadTemplates(dir string) (*template.Template, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+_TemplateExt))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no %s templates found in %s", _TemplateExt, dir)
+	}
+	return template.New("").Funcs(FuncMap(nil)).ParseFiles(files...)
+}
+
+// TemplateDir 返回插件参数 templates 指定的模板目录,没有指定时返回空字符串
+func (r *Registry) TemplateDir() string {
+	dir, _ := r.Params().Get(ParamTemplates)
+	return dir
+}
+
+// ExecuteTemplate 以data为数据执行tmpl中名为name的模板,并将结果写入生成文件g.
+// 模板中的函数会绑定到g,所以用到的包都会出现在g的导入中.
+func ExecuteTemplate(g *GeneratedFile, tmpl *template.Template, name string, data interface{}) error {
+	t, err := tmpl.Clone()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := t.Funcs(FuncMap(g)).ExecuteTemplate(&buf, name, data); err != nil {
+		return err
+	}
+	if buf.Len() > 0 && !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	_, err = g.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,79 @@
+package gengo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// _WriteTemplate 在dir中写入一个模板文件
+func _WriteTemplate(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write template %s: %v", name, err)
+	}
+}
+
+func TestExecuteTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gengo-template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	_WriteTemplate(t, dir, "handler.tmpl", `{{define "handler"}}var _ = {{Qualify "net/http" "StatusOK"}}
+const {{Title .}} = "{{Snake .}}"{{end}}`)
+
+	tmpl, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatalf("LoadTemplates() failed: %v", err)
+	}
+	g := NewGeneratedFile("out.go", GoPackage{Path: "example.com/out", Name: "out"})
+	if err := ExecuteTemplate(g, tmpl, "handler", "get_HTTPServer"); err != nil {
+		t.Fatalf("ExecuteTemplate() failed: %v", err)
+	}
+	out, err := g.Content()
+	if err != nil {
+		t.Fatalf("Content() failed: %v", err)
+	}
+	for _, want := range []string{`import (`, `"net/http"`, `var _ = http.StatusOK`, `const GetHTTPServer = "get_http_server"`} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("generated file does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestLoadedTemplateWithoutGeneratedFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gengo-template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	_WriteTemplate(t, dir, "a.tmpl", `{{define "import"}}{{Import "fmt"}}{{end}}`+
+		`{{define "qualify"}}{{Qualify "fmt" "Println"}}{{end}}`+
+		`{{define "gotype"}}{{GoType .}}{{end}}`)
+
+	tmpl, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatalf("LoadTemplates() failed: %v", err)
+	}
+	for _, name := range []string{"import", "qualify", "gotype"} {
+		var b strings.Builder
+		err := tmpl.ExecuteTemplate(&b, name, &Message{})
+		if err == nil || !strings.Contains(err.Error(), "ExecuteTemplate") {
+			t.Errorf("executing %s directly = %v, want an error pointing to ExecuteTemplate", name, err)
+		}
+	}
+}
+
+func TestLoadTemplatesEmptyDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gengo-template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if _, err := LoadTemplates(dir); err == nil {
+		t.Errorf("LoadTemplates() on an empty directory succeeded, want error")
+	}
+}
@@ -6,22 +6,28 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"sort"
 	"strings"
 
 	plugin "github.com/yuansudong/gengo/plugin"
 	"google.golang.org/protobuf/proto"
 )
 
-// NameUpper 名字命名为大写
+// NameUpper 名字命名为大写,按下划线分段后每段首字母大写,
+// 连续的下划线以及开头和结尾的下划线产生的空段会被忽略
 func NameUpper(str string) string {
 	arr := strings.Split(str, "_")
 	for index, val := range arr {
-		arr[index] = strings.ToUpper(val[:1]) + val[1:]
+		if val != "" {
+			arr[index] = strings.ToUpper(val[:1]) + val[1:]
+		}
 	}
 	return strings.Join(arr, "")
 }
 
-// GetRequest 用于从一个标准输入中,获取一个解析请求
+// GetRequest 用于从一个标准输入中,获取一个解析请求.
+// 设置了环境变量 GENGO_DUMP_REQUEST 或插件参数 dump_request 时,会把请求保存下来用于Replay,
+// 保存失败只会向WarningWriter输出警告,不影响代码生成.
 func GetRequest(r io.Reader) (*plugin.CodeGeneratorRequest, error) {
 	input, err := ioutil.ReadAll(r)
 	if err != nil {
@@ -31,42 +37,88 @@ func GetRequest(r io.Reader) (*plugin.CodeGeneratorRequest, error) {
 	if err = proto.Unmarshal(input, req); err != nil {
 		return nil, fmt.Errorf("读取标准输入失败: %v", err)
 	}
+	if p := _DumpPath(req); p != "" {
+		if err := DumpRequest(req, p); err != nil {
+			fmt.Fprintf(WarningWriter, "warning: 保存请求失败: %v\n", err)
+		}
+	}
 	return req, nil
 }
 
-// WriteFiles 用于写入一批响应到标准输出
+// Run 从r读取请求,加载Registry后调用gen,并把响应写入w.
+// gen返回的错误会写入响应的Error字段交给protoc报告,
+// Run只在读取请求或写入响应失败时返回错误,不会退出进程.
+func Run(r io.Reader, w io.Writer, gen Generator) error {
+	req, err := GetRequest(r)
+	if err != nil {
+		return err
+	}
+	return WriteResponseTo(w, Generate(req, gen))
+}
+
+// Main 是插件的入口,使用标准输入输出调用Run,失败时以状态码1退出
+func Main(gen Generator) {
+	if err := Run(os.Stdin, os.Stdout, gen); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// WriteFiles 用于写入一批响应到标准输出,失败时退出进程.
+// 需要处理错误时使用 WriteResponseTo.
 func WriteFiles(files []*plugin.CodeGeneratorResponse_File) {
 	WriteResponse(&plugin.CodeGeneratorResponse{File: files})
 }
 
-// WriteError 用于写一个错误到标准输出
+// WriteError 用于写一个错误到标准输出,失败时退出进程
 func WriteError(err error) {
 	WriteResponse(&plugin.CodeGeneratorResponse{Error: proto.String(err.Error())})
 }
 
-// WriteResponse 用于向标准输出中写数据
+// SupportedFeatures 是gengo向protoc声明支持的特性
+var SupportedFeatures = uint64(plugin.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+
+// WriteResponse 用于向标准输出中写数据,失败时退出进程
 func WriteResponse(rsp *plugin.CodeGeneratorResponse) {
+	if err := WriteResponseTo(os.Stdout, rsp); err != nil {
+		log.Fatalln(err.Error())
+	}
+}
+
+// WriteResponseTo 将响应序列化后写入w
+func WriteResponseTo(w io.Writer, rsp *plugin.CodeGeneratorResponse) error {
+	if rsp.SupportedFeatures == nil {
+		rsp.SupportedFeatures = proto.Uint64(SupportedFeatures)
+	}
 	buf, err := proto.Marshal(rsp)
 	if err != nil {
-		log.Fatalln(err.Error())
+		return fmt.Errorf("序列化响应失败: %v", err)
 	}
-	if _, err := os.Stdout.Write(buf); err != nil {
-		log.Fatalln(err.Error())
+	if _, err := w.Write(buf); err != nil {
+		return fmt.Errorf("写入响应失败: %v", err)
 	}
+	return nil
 }
 
-// ReplaceArgs 用于替代参数
+// ReplaceArgs 用于替代参数.
+// 较长的键优先匹配,键之间互相重叠时结果也是确定的.
+// 新代码建议使用 ExecuteTemplate.
 func ReplaceArgs(str string, args map[string]interface{}) string {
-
-	for key, val := range args {
-		var tmpV string
-		switch val.(type) {
-		default:
-			tmpV = fmt.Sprint(val)
+	keys := make([]string, 0, len(args))
+	for key := range args {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
 		}
-		str = strings.Replace(str, key, tmpV, -1)
+		return keys[i] < keys[j]
+	})
+	pairs := make([]string, 0, 2*len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key, fmt.Sprint(args[key]))
 	}
-	return str
+	return strings.NewReplacer(pairs...).Replace(str)
 }
 
 // FieldDescriptorProto_TYPE_DOUBLE FieldDescriptorProto_Type = 1
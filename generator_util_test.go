@@ -0,0 +1,142 @@
+package gengo
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	descriptor "github.com/yuansudong/gengo/descriptor"
+	plugin "github.com/yuansudong/gengo/plugin"
+	"google.golang.org/protobuf/proto"
+)
+
+// _RequestReader 把请求序列化为protoc写给插件的标准输入
+func _RequestReader(t *testing.T, req *plugin.CodeGeneratorRequest) *bytes.Reader {
+	t.Helper()
+	buf, err := proto.Marshal(req)
+	if err != nil {
+		t.Fatalf("failed to marshal request: %v", err)
+	}
+	return bytes.NewReader(buf)
+}
+
+// _CaptureWarnings 在测试期间把WarningWriter替换为一个缓冲区
+func _CaptureWarnings(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	old := WarningWriter
+	WarningWriter = &buf
+	t.Cleanup(func() { WarningWriter = old })
+	return &buf
+}
+
+func TestGetRequestDump(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gengo-dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "req.bin")
+	req := &plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"foo/a.proto"},
+		Parameter:      proto.String("dump_request=" + path),
+	}
+	got, err := GetRequest(_RequestReader(t, req))
+	if err != nil {
+		t.Fatalf("GetRequest() failed: %v", err)
+	}
+	if !proto.Equal(got, req) {
+		t.Errorf("GetRequest() = %v, want %v", got, req)
+	}
+	for _, p := range []string{path, path + ".json"} {
+		dumped, err := ReadRequestFile(p)
+		if err != nil {
+			t.Fatalf("ReadRequestFile(%s) failed: %v", p, err)
+		}
+		if !proto.Equal(dumped, req) {
+			t.Errorf("ReadRequestFile(%s) = %v, want %v", p, dumped, req)
+		}
+	}
+}
+
+func TestGetRequestDumpFailure(t *testing.T) {
+	warnings := _CaptureWarnings(t)
+	req := &plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"foo/a.proto"},
+		Parameter:      proto.String("dump_request=" + filepath.Join(os.DevNull, "no", "such", "dir", "req.bin")),
+	}
+	got, err := GetRequest(_RequestReader(t, req))
+	if err != nil {
+		t.Fatalf("GetRequest() failed on an unwritable dump path: %v", err)
+	}
+	if !proto.Equal(got, req) {
+		t.Errorf("GetRequest() = %v, want %v", got, req)
+	}
+	if !strings.HasPrefix(warnings.String(), "warning: ") {
+		t.Errorf("GetRequest() warning = %q, want a warning about the dump", warnings.String())
+	}
+}
+
+func TestNameUpper(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want string
+	}{
+		{in: "foo_bar", want: "FooBar"},
+		{in: "fooBar", want: "FooBar"},
+		// 空的分隔段曾经导致panic
+		{in: "foo__bar", want: "FooBar"},
+		{in: "foo_bar_", want: "FooBar"},
+		{in: "_foo", want: "Foo"},
+		{in: "_", want: ""},
+		{in: "", want: ""},
+	} {
+		if got := NameUpper(tc.in); got != tc.want {
+			t.Errorf("NameUpper(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+// _FailingWriter 的每次写入都会失败
+type _FailingWriter struct{}
+
+func (_FailingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestRun(t *testing.T) {
+	req := &plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"foo/a.proto"},
+		ProtoFile: []*descriptor.FileDescriptorProto{{
+			Name:    proto.String("foo/a.proto"),
+			Package: proto.String("foo"),
+			Options: &descriptor.FileOptions{GoPackage: proto.String("example.com/foo")},
+		}},
+	}
+	gen := func(reg *Registry) ([]*plugin.CodeGeneratorResponse_File, error) {
+		return []*plugin.CodeGeneratorResponse_File{{Name: proto.String("a.txt"), Content: proto.String("a")}}, nil
+	}
+	var out bytes.Buffer
+	if err := Run(_RequestReader(t, req), &out, gen); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	rsp := new(plugin.CodeGeneratorResponse)
+	if err := proto.Unmarshal(out.Bytes(), rsp); err != nil {
+		t.Fatal(err)
+	}
+	if len(rsp.GetFile()) != 1 || rsp.GetFile()[0].GetName() != "a.txt" {
+		t.Errorf("Run() response files = %v, error %q; want a.txt", rsp.GetFile(), rsp.GetError())
+	}
+	if rsp.GetSupportedFeatures() != SupportedFeatures {
+		t.Errorf("Run() supported features = %d, want %d", rsp.GetSupportedFeatures(), SupportedFeatures)
+	}
+
+	// 写入失败时返回错误而不是退出进程
+	if err := Run(_RequestReader(t, req), _FailingWriter{}, gen); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("Run() with a failing writer = %v, want the write error", err)
+	}
+}
@@ -0,0 +1,48 @@
+package gengo
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// ParamWarningsAsErrors 为true时,生成器报告的警告会被当作错误
+const ParamWarningsAsErrors = "warnings_as_errors"
+
+// WarningWriter 是Generate输出警告的地方,默认为标准错误,protoc会把插件的标准错误原样输出
+var WarningWriter io.Writer = os.Stderr
+
+// Warnf 报告一个不影响生成的问题,例如使用了废弃的特性,忽略了某个选项,
+// 或者类型映射会丢失信息. 警告会以protoc的格式输出到WarningWriter.
+func (r *Registry) Warnf(elem Element, format string, args ...interface{}) {
+	r._Diags.Warnf(elem, format, args...)
+}
+
+// WarningsAsErrors 判断是否设置了插件参数 warnings_as_errors,
+// 参数值在ApplyParams中已经检查过
+func (r *Registry) WarningsAsErrors() bool {
+	b, _ := r.Params().Bool(ParamWarningsAsErrors)
+	return b
+}
+
+// _ReportWarnings 输出reg中收集的警告. 设置了warnings_as_errors时,
+// 警告会被提升为错误返回.
+func _ReportWarnings(reg *Registry) Diagnostics {
+	warnings := reg.Diagnostics().Warnings()
+	if len(warnings) == 0 {
+		return nil
+	}
+	if reg.WarningsAsErrors() {
+		errs := make(Diagnostics, 0, len(warnings))
+		for _, w := range warnings {
+			promoted := *w
+			promoted.Severity = SeverityError
+			errs = append(errs, &promoted)
+		}
+		return errs
+	}
+	for _, w := range warnings {
+		fmt.Fprintln(WarningWriter, w.Error())
+	}
+	return nil
+}
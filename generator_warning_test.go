@@ -0,0 +1,94 @@
+package gengo
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	descriptor "github.com/yuansudong/gengo/descriptor"
+	plugin "github.com/yuansudong/gengo/plugin"
+	"google.golang.org/protobuf/proto"
+)
+
+// _WarningRequest 返回只包含foo/a.proto的请求
+func _WarningRequest(param string) *plugin.CodeGeneratorRequest {
+	return &plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"foo/a.proto"},
+		Parameter:      proto.String(param),
+		ProtoFile: []*descriptor.FileDescriptorProto{{
+			Name:    proto.String("foo/a.proto"),
+			Package: proto.String("foo"),
+			Syntax:  proto.String("proto3"),
+			Options: &descriptor.FileOptions{GoPackage: proto.String("example.com/foo")},
+		}},
+	}
+}
+
+// _WarnAndFail 返回一个对每个目标文件报告警告,最后返回err的生成器
+func _WarnAndFail(err error) Generator {
+	return func(reg *Registry) ([]*plugin.CodeGeneratorResponse_File, error) {
+		for _, f := range reg.TargetFiles() {
+			reg.Warnf(f, "something is deprecated")
+		}
+		return nil, err
+	}
+}
+
+func TestGenerateWarnings(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		param        string
+		genErr       error
+		wantError    string
+		wantWarnings string
+	}{
+		{
+			name:         "printed",
+			wantWarnings: "foo/a.proto: warning: something is deprecated\n",
+		},
+		{
+			name:      "promoted",
+			param:     "warnings_as_errors",
+			wantError: "foo/a.proto: something is deprecated",
+		},
+		{
+			name:      "promoted with generator error",
+			param:     "warnings_as_errors=true",
+			genErr:    errors.New("boom"),
+			wantError: "boom\nfoo/a.proto: something is deprecated",
+		},
+		{
+			name:      "promoted with generator diagnostic",
+			param:     "warnings_as_errors=true",
+			genErr:    &Diagnostic{File: "foo/a.proto", Line: 3, Column: 1, Message: "bad"},
+			wantError: "foo/a.proto:3:1: bad\nfoo/a.proto: something is deprecated",
+		},
+		{
+			name:         "generator error without promotion",
+			param:        "warnings_as_errors=false",
+			genErr:       errors.New("boom"),
+			wantError:    "boom",
+			wantWarnings: "foo/a.proto: warning: something is deprecated\n",
+		},
+		{
+			name:      "invalid parameter",
+			param:     "warnings_as_errors=yes",
+			wantError: `invalid value "yes" for parameter warnings_as_errors`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			warnings := _CaptureWarnings(t)
+			rsp := Generate(_WarningRequest(tc.param), _WarnAndFail(tc.genErr))
+			if tc.wantError == "" {
+				if rsp.Error != nil {
+					t.Errorf("Generate() error = %q, want none", rsp.GetError())
+				}
+			} else if !strings.HasPrefix(rsp.GetError(), tc.wantError) {
+				t.Errorf("Generate() error = %q, want %q", rsp.GetError(), tc.wantError)
+			}
+			if got := warnings.String(); got != tc.wantWarnings {
+				t.Errorf("warnings = %q, want %q", got, tc.wantWarnings)
+			}
+		})
+	}
+}
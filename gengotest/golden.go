@@ -0,0 +1,397 @@
+// Package gengotest 为基于gengo的代码生成器提供golden文件测试.
+//
+// 在测试中构造一个CodeGeneratorRequest,交给生成器执行,
+// 再把生成的文件与testdata中的golden文件逐个比较:
+//
+//	func TestGenerate(t *testing.T) {
+//		req := gengotest.NewRequest(fds, []string{"foo/a.proto"}, "paths=source_relative")
+//		gengotest.Run(t, req, generate, "testdata/golden")
+//	}
+//
+// 使用 go test -update 或者设置环境变量 GENGO_UPDATE_GOLDEN=1 重新生成golden文件.
+// -update 由gengotest注册,测试包不需要也不能再定义同名的标志;
+// 在 go test ./... 中部分包没有引用gengotest时,使用环境变量.
+//
+// 更新时只会写入生成的文件,并删除同一个测试上次生成而这次没有生成的文件,
+// goldenDir中的其他文件不受影响,因此goldenDir可以是与其他测试共用的testdata.
+// 每个测试写入的文件记录在goldenDir下的 .gengotest-<测试名> 中.
+package gengotest
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/yuansudong/gengo"
+	descriptor "github.com/yuansudong/gengo/descriptor"
+	plugin "github.com/yuansudong/gengo/plugin"
+	"google.golang.org/protobuf/proto"
+)
+
+// EnvUpdate 设置为true时Run用生成的内容覆盖golden文件,与 -update 标志作用相同
+const EnvUpdate = "GENGO_UPDATE_GOLDEN"
+
+// _Update 为true时用生成的内容覆盖golden文件
+var _Update = flag.Bool("update", false, "update gengotest golden files")
+
+// _ShouldUpdate 判断是否需要覆盖golden文件
+func _ShouldUpdate() bool {
+	if *_Update {
+		return true
+	}
+	update, _ := strconv.ParseBool(os.Getenv(EnvUpdate))
+	return update
+}
+
+// NewRequest 用一个FileDescriptorSet构造CodeGeneratorRequest.
+// fds中的文件需要按依赖顺序排列,targets是要生成代码的文件,param是插件参数.
+func NewRequest(fds *descriptor.FileDescriptorSet, targets []string, param string) *plugin.CodeGeneratorRequest {
+	req := &plugin.CodeGeneratorRequest{
+		FileToGenerate: targets,
+		ProtoFile:      fds.GetFile(),
+	}
+	if param != "" {
+		req.Parameter = proto.String(param)
+	}
+	return req
+}
+
+// ReadFileDescriptorSet 读取 protoc --descriptor_set_out 生成的文件
+func ReadFileDescriptorSet(path string) (*descriptor.FileDescriptorSet, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fds := new(descriptor.FileDescriptorSet)
+	if err := proto.Unmarshal(buf, fds); err != nil {
+		return nil, err
+	}
+	return fds, nil
+}
+
+// Run 用gen处理req,并将生成的每个文件与goldenDir下同名的文件比较.
+// 不一致时以unified diff的形式报告; 带 -update 运行时改为写入golden文件.
+func Run(t testing.TB, req *plugin.CodeGeneratorRequest, gen gengo.Generator, goldenDir string) {
+	t.Helper()
+	rsp := gengo.Generate(req, gen)
+	if rsp.Error != nil {
+		t.Fatalf("generator failed: %s", rsp.GetError())
+	}
+	generated := _ResponseFiles(rsp)
+	manifest := filepath.Join(goldenDir, _ManifestName(t.Name()))
+	if _ShouldUpdate() {
+		if err := _UpdateGolden(goldenDir, manifest, rsp, generated); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	previous, err := _ReadManifest(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range _SortedKeys(generated) {
+		buf, err := ioutil.ReadFile(filepath.Join(goldenDir, filepath.FromSlash(name)))
+		if os.IsNotExist(err) {
+			t.Errorf("%s: generated but there is no golden file (run with -update to create it)", name)
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if golden := string(buf); golden != generated[name] {
+			t.Errorf("%s: content differs from golden file (-golden +generated):\n%s", name, Diff(golden, generated[name]))
+		}
+	}
+	for _, name := range previous {
+		if _, ok := generated[name]; ok {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(goldenDir, filepath.FromSlash(name))); err == nil {
+			t.Errorf("%s: golden file exists but was not generated", name)
+		}
+	}
+}
+
+// _ResponseFiles 按protoc的规则合并响应中的文件,没有文件名的条目追加到上一个文件
+func _ResponseFiles(rsp *plugin.CodeGeneratorResponse) map[string]string {
+	files := make(map[string]string)
+	var last string
+	for _, f := range rsp.GetFile() {
+		if f.GetName() != "" {
+			last = f.GetName()
+		}
+		files[last] += f.GetContent()
+	}
+	return files
+}
+
+// _ManifestName 返回记录测试写入了哪些golden文件的文件名
+func _ManifestName(test string) string {
+	return ".gengotest-" + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, test)
+}
+
+// _ReadManifest 读取上次更新时写入的文件名,记录不存在时返回空
+func _ReadManifest(path string) ([]string, error) {
+	buf, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, name := range strings.Split(string(buf), "\n") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// _UpdateGolden 写入生成的文件,删除上次由同一个测试写入而这次没有生成的文件,并更新记录.
+// 不在记录中的文件不会被删除.
+func _UpdateGolden(dir, manifest string, rsp *plugin.CodeGeneratorResponse, generated map[string]string) error {
+	previous, err := _ReadManifest(manifest)
+	if err != nil {
+		return err
+	}
+	if err := gengo.WriteResponseFiles(dir, rsp); err != nil {
+		return err
+	}
+	for _, name := range previous {
+		if _, ok := generated[name]; ok {
+			continue
+		}
+		if err := gengo.CheckOutputName(name); err != nil {
+			return fmt.Errorf("%s: %v", manifest, err)
+		}
+		if err := os.Remove(filepath.Join(dir, filepath.FromSlash(name))); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	names := _SortedKeys(generated)
+	if len(names) == 0 {
+		if err := os.Remove(manifest); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(manifest, []byte(strings.Join(names, "\n")+"\n"), 0644)
+}
+
+// _SortedKeys 返回排好序的键
+func _SortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// _DiffLine 是diff中的一行, op 为 ' ', '-' 或 '+', ai 和 bi 是这一行在a和b中的下标
+type _DiffLine struct {
+	op   byte
+	text string
+	ai   int
+	bi   int
+}
+
+// _DiffContext unified diff中变化前后保留的行数
+const _DiffContext = 3
+
+// Diff 按行比较a和b,返回unified diff格式的差异,a中的行以 - 开头,b中的行以 + 开头.
+// 使用Myers的线性空间算法,内存与行数成正比,时间与行数乘以差异的行数成正比.
+func Diff(a, b string) string {
+	d := &_Differ{a: strings.Split(a, "\n"), b: strings.Split(b, "\n")}
+	d._Diff(0, len(d.a), 0, len(d.b))
+	lines := d.lines
+	var out strings.Builder
+	for start := 0; start < len(lines); {
+		if lines[start].op == ' ' {
+			start++
+			continue
+		}
+		// 找到一个hunk: 向前后扩展上下文,合并距离较近的变化
+		lo := start - _DiffContext
+		if lo < 0 {
+			lo = 0
+		}
+		hi := start
+		for k := start; k < len(lines) && k <= hi+2*_DiffContext; k++ {
+			if lines[k].op != ' ' {
+				hi = k
+			}
+		}
+		hi += _DiffContext
+		if hi >= len(lines) {
+			hi = len(lines) - 1
+		}
+		var aLen, bLen int
+		for _, l := range lines[lo : hi+1] {
+			if l.op != '+' {
+				aLen++
+			}
+			if l.op != '-' {
+				bLen++
+			}
+		}
+		out.WriteString(_HunkHeader(lines[lo].ai, aLen, lines[lo].bi, bLen))
+		for _, l := range lines[lo : hi+1] {
+			out.WriteByte(l.op)
+			out.WriteString(l.text)
+			out.WriteByte('\n')
+		}
+		start = hi + 1
+	}
+	return out.String()
+}
+
+// _HunkHeader 返回hunk的头部,例如 @@ -1,3 +1,4 @@.
+// aStart 和 bStart 是hunk第一行的下标; 与diff -u 一致,长度为0的一侧显示的是hunk之前那一行的行号.
+func _HunkHeader(aStart, aLen, bStart, bLen int) string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", _HunkStart(aStart, aLen), aLen, _HunkStart(bStart, bLen), bLen)
+}
+
+// _HunkStart 将下标转换为hunk头部中从1开始的行号
+func _HunkStart(index, length int) int {
+	if length == 0 {
+		return index
+	}
+	return index + 1
+}
+
+// _Differ 计算两组行之间的最短编辑序列
+type _Differ struct {
+	a, b  []string
+	lines []_DiffLine
+}
+
+// _Diff 比较 a[a0:a1] 与 b[b0:b1],按顺序把结果追加到lines
+func (d *_Differ) _Diff(a0, a1, b0, b1 int) {
+	for a0 < a1 && b0 < b1 && d.a[a0] == d.b[b0] {
+		d.lines = append(d.lines, _DiffLine{' ', d.a[a0], a0, b0})
+		a0++
+		b0++
+	}
+	suffix := 0
+	for a1 > a0 && b1 > b0 && d.a[a1-1] == d.b[b1-1] {
+		a1--
+		b1--
+		suffix++
+	}
+	if a0 == a1 || b0 == b1 {
+		d._Replace(a0, a1, b0, b1)
+	} else if x, y, ok := d._Bisect(a0, a1, b0, b1); ok {
+		d._Diff(a0, x, b0, y)
+		d._Diff(x, a1, y, b1)
+	} else {
+		d._Replace(a0, a1, b0, b1)
+	}
+	for i := 0; i < suffix; i++ {
+		d.lines = append(d.lines, _DiffLine{' ', d.a[a1+i], a1 + i, b1 + i})
+	}
+}
+
+// _Replace 将 a[a0:a1] 全部删除,再插入 b[b0:b1]
+func (d *_Differ) _Replace(a0, a1, b0, b1 int) {
+	for i := a0; i < a1; i++ {
+		d.lines = append(d.lines, _DiffLine{'-', d.a[i], i, b0})
+	}
+	for j := b0; j < b1; j++ {
+		d.lines = append(d.lines, _DiffLine{'+', d.b[j], a1, j})
+	}
+}
+
+// _Bisect 找到 a[a0:a1] 与 b[b0:b1] 最短编辑路径上的中间点(x, y),
+// 从两端同时搜索,只需要两个长度与行数成正比的数组
+func (d *_Differ) _Bisect(a0, a1, b0, b1 int) (int, int, bool) {
+	n, m := a1-a0, b1-b0
+	maxD := (n + m + 1) / 2
+	offset := maxD + 1
+	v1 := make([]int, 2*offset+1)
+	v2 := make([]int, 2*offset+1)
+	for i := range v1 {
+		v1[i] = -1
+		v2[i] = -1
+	}
+	v1[offset+1] = 0
+	v2[offset+1] = 0
+	delta := n - m
+	// 差值为奇数时在正向搜索中检查重叠,否则在反向搜索中检查
+	front := delta%2 != 0
+	var k1start, k1end, k2start, k2end int
+	for step := 0; step < maxD; step++ {
+		for k1 := -step + k1start; k1 <= step-k1end; k1 += 2 {
+			i := offset + k1
+			var x1 int
+			if k1 == -step || (k1 != step && v1[i-1] < v1[i+1]) {
+				x1 = v1[i+1]
+			} else {
+				x1 = v1[i-1] + 1
+			}
+			y1 := x1 - k1
+			for x1 < n && y1 < m && d.a[a0+x1] == d.b[b0+y1] {
+				x1++
+				y1++
+			}
+			v1[i] = x1
+			switch {
+			case x1 > n:
+				k1end += 2
+			case y1 > m:
+				k1start += 2
+			case front:
+				j := offset + delta - k1
+				if j >= 0 && j < len(v2) && v2[j] != -1 && x1 >= n-v2[j] {
+					return a0 + x1, b0 + y1, true
+				}
+			}
+		}
+		for k2 := -step + k2start; k2 <= step-k2end; k2 += 2 {
+			i := offset + k2
+			var x2 int
+			if k2 == -step || (k2 != step && v2[i-1] < v2[i+1]) {
+				x2 = v2[i+1]
+			} else {
+				x2 = v2[i-1] + 1
+			}
+			y2 := x2 - k2
+			for x2 < n && y2 < m && d.a[a1-x2-1] == d.b[b1-y2-1] {
+				x2++
+				y2++
+			}
+			v2[i] = x2
+			switch {
+			case x2 > n:
+				k2end += 2
+			case y2 > m:
+				k2start += 2
+			case !front:
+				j := offset + delta - k2
+				if j >= 0 && j < len(v1) && v1[j] != -1 {
+					x1 := v1[j]
+					y1 := x1 - (j - offset)
+					if x1 >= n-x2 {
+						return a0 + x1, b0 + y1, true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,338 @@
+package gengotest
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yuansudong/gengo"
+	descriptor "github.com/yuansudong/gengo/descriptor"
+	plugin "github.com/yuansudong/gengo/plugin"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestUpdateFlag(t *testing.T) {
+	if flag.Lookup("update") == nil {
+		t.Errorf("gengotest does not register the -update flag")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{name: "equal", a: "a\nb\n", b: "a\nb\n", want: ""},
+		{
+			name: "change",
+			a:    "a\nb\nc\n",
+			b:    "a\nx\nc\n",
+			want: "@@ -1,4 +1,4 @@\n a\n-b\n+x\n c\n \n",
+		},
+		{
+			name: "insert",
+			a:    "a\nc",
+			b:    "a\nb\nc",
+			want: "@@ -1,2 +1,3 @@\n a\n+b\n c\n",
+		},
+		{
+			name: "delete at start",
+			a:    "x\na\nb",
+			b:    "a\nb",
+			want: "@@ -1,3 +1,2 @@\n-x\n a\n b\n",
+		},
+		{
+			name: "separate hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12",
+			b:    "0\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n13",
+			want: "@@ -1,4 +1,4 @@\n-1\n+0\n 2\n 3\n 4\n" +
+				"@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+13\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Diff(tc.a, tc.b); got != tc.want {
+				t.Errorf("Diff() =\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHunkHeader(t *testing.T) {
+	for _, tc := range []struct {
+		aStart, aLen, bStart, bLen int
+		want                       string
+	}{
+		{0, 3, 0, 4, "@@ -1,3 +1,4 @@\n"},
+		// 长度为0的一侧显示hunk之前那一行的行号
+		{0, 0, 0, 2, "@@ -0,0 +1,2 @@\n"},
+		{4, 0, 4, 1, "@@ -4,0 +5,1 @@\n"},
+		{7, 2, 7, 0, "@@ -8,2 +7,0 @@\n"},
+	} {
+		if got := _HunkHeader(tc.aStart, tc.aLen, tc.bStart, tc.bLen); got != tc.want {
+			t.Errorf("_HunkHeader(%d, %d, %d, %d) = %q, want %q", tc.aStart, tc.aLen, tc.bStart, tc.bLen, got, tc.want)
+		}
+	}
+}
+
+// _LCSLength 用动态规划计算最长公共子序列的长度,作为Diff的参照
+func _LCSLength(x, y []string) int {
+	prev := make([]int, len(y)+1)
+	for i := range x {
+		cur := make([]int, len(y)+1)
+		for j := range y {
+			switch {
+			case x[i] == y[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] >= cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev = cur
+	}
+	return prev[len(y)]
+}
+
+func TestDiffIsMinimal(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	randLines := func() []string {
+		lines := make([]string, rnd.Intn(12))
+		for i := range lines {
+			lines[i] = string(rune('a' + rnd.Intn(4)))
+		}
+		return lines
+	}
+	for i := 0; i < 2000; i++ {
+		x, y := randLines(), randLines()
+		d := &_Differ{a: x, b: y}
+		d._Diff(0, len(x), 0, len(y))
+		var gotA, gotB []string
+		common := 0
+		for _, l := range d.lines {
+			if l.op != '+' {
+				gotA = append(gotA, l.text)
+			}
+			if l.op != '-' {
+				gotB = append(gotB, l.text)
+			}
+			if l.op == ' ' {
+				common++
+			}
+		}
+		if strings.Join(gotA, ",") != strings.Join(x, ",") || strings.Join(gotB, ",") != strings.Join(y, ",") {
+			t.Fatalf("diff of %q and %q does not reproduce the inputs: %v", x, y, d.lines)
+		}
+		if want := _LCSLength(x, y); common != want {
+			t.Fatalf("diff of %q and %q keeps %d common lines, want %d", x, y, common, want)
+		}
+	}
+}
+
+func TestDiffLargeInput(t *testing.T) {
+	// 按完整的LCS表计算时需要 n*n 个整数,这里约为32GB
+	const n, every = 64 << 10, 4096
+	var a, b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&a, "line %d\n", i)
+		if i%every == every/2 {
+			fmt.Fprintf(&b, "changed %d\n", i)
+			continue
+		}
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+	got := Diff(a.String(), b.String())
+	if hunks := strings.Count(got, "@@ -"); hunks != n/every {
+		t.Errorf("Diff() has %d hunks, want %d", hunks, n/every)
+	}
+	i := every/2 + every
+	want := fmt.Sprintf("@@ -%d,7 +%d,7 @@\n line %d\n line %d\n line %d\n-line %d\n+changed %d\n line %d\n line %d\n line %d\n",
+		i-2, i-2, i-3, i-2, i-1, i, i, i+1, i+2, i+3)
+	if !strings.Contains(got, want) {
+		t.Errorf("Diff() does not contain\n%s", want)
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := _TempDir(t)
+	// goldenDir中已有的其他文件不属于这个测试,更新时不能删除
+	_WriteFile(t, filepath.Join(dir, "input.proto"), "syntax = \"proto3\";\n")
+	_WriteFile(t, filepath.Join(dir, "foo", "other.txt"), "other\n")
+	req := NewRequest(_FileDescriptorSet(), []string{"foo/a.proto"}, "paths=source_relative")
+
+	_RunUpdate(t, req, _ListMessages, dir)
+	want := map[string]string{
+		"input.proto":           "syntax = \"proto3\";\n",
+		"foo/other.txt":         "other\n",
+		"foo/a.txt":             "foo.Req\n",
+		_ManifestName(t.Name()): "foo/a.txt\n",
+	}
+	if got := _ReadDir(t, dir); !reflect.DeepEqual(got, want) {
+		t.Fatalf("golden files after update = %v, want %v", got, want)
+	}
+
+	// 内容一致时不报告错误
+	Run(t, req, _ListMessages, dir)
+
+	// 内容不一致以及缺少golden文件时都会报告,不属于这个测试的文件不会报告
+	_WriteFile(t, filepath.Join(dir, "foo", "a.txt"), "foo.Other\n")
+	rec := &_Recorder{TB: t}
+	Run(rec, req, _ListMessages, dir)
+	wantErrors := []string{
+		"foo/a.txt: content differs from golden file (-golden +generated):\n@@ -1,2 +1,2 @@\n-foo.Other\n+foo.Req\n \n",
+	}
+	if !reflect.DeepEqual(rec.errors, wantErrors) {
+		t.Errorf("Run() reported %q, want %q", rec.errors, wantErrors)
+	}
+	os.Remove(filepath.Join(dir, "foo", "a.txt"))
+	rec = &_Recorder{TB: t}
+	Run(rec, req, _ListMessages, dir)
+	if len(rec.errors) != 1 || !strings.HasPrefix(rec.errors[0], "foo/a.txt: generated but there is no golden file") {
+		t.Errorf("Run() reported %q, want a missing golden file", rec.errors)
+	}
+}
+
+func TestRunRemovesStaleFiles(t *testing.T) {
+	dir := _TempDir(t)
+	_WriteFile(t, filepath.Join(dir, "keep.txt"), "keep\n")
+	both := NewRequest(_FileDescriptorSet(), []string{"foo/a.proto", "bar/b.proto"}, "multi_package,paths=source_relative")
+	one := NewRequest(_FileDescriptorSet(), []string{"foo/a.proto"}, "multi_package,paths=source_relative")
+	_RunUpdate(t, both, _ListMessages, dir)
+
+	// 上次生成而这次没有生成的golden文件会报告
+	rec := &_Recorder{TB: t}
+	Run(rec, one, _ListMessages, dir)
+	if want := []string{"bar/b.txt: golden file exists but was not generated"}; !reflect.DeepEqual(rec.errors, want) {
+		t.Errorf("Run() reported %q, want %q", rec.errors, want)
+	}
+
+	// 更新时只删除这个测试上次写入的文件
+	_RunUpdate(t, one, _ListMessages, dir)
+	want := map[string]string{
+		"keep.txt":              "keep\n",
+		"foo/a.txt":             "foo.Req\n",
+		_ManifestName(t.Name()): "foo/a.txt\n",
+	}
+	if got := _ReadDir(t, dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("golden files after update = %v, want %v", got, want)
+	}
+}
+
+func TestManifestName(t *testing.T) {
+	if got, want := _ManifestName("TestGen/proto3 optional"), ".gengotest-TestGen_proto3_optional"; got != want {
+		t.Errorf("_ManifestName() = %q, want %q", got, want)
+	}
+}
+
+// _RunUpdate 以更新模式执行Run
+func _RunUpdate(t *testing.T, req *plugin.CodeGeneratorRequest, gen gengo.Generator, dir string) {
+	t.Helper()
+	os.Setenv(EnvUpdate, "true")
+	defer os.Unsetenv(EnvUpdate)
+	Run(t, req, gen, dir)
+}
+
+// _ReadDir 读取目录下所有文件,键是以 / 分隔的相对路径
+func _ReadDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		buf, err := ioutil.ReadFile(p)
+		files[filepath.ToSlash(rel)] = string(buf)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// _Recorder 记录Run报告的错误,而不是让测试失败
+type _Recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *_Recorder) Helper() {}
+
+func (r *_Recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+// _ListMessages 为每个目标文件生成一个列出其中消息的文本文件
+func _ListMessages(reg *gengo.Registry) ([]*plugin.CodeGeneratorResponse_File, error) {
+	var files []*plugin.CodeGeneratorResponse_File
+	for _, f := range reg.TargetFiles() {
+		var b strings.Builder
+		for _, m := range f.Messages {
+			fmt.Fprintln(&b, strings.TrimPrefix(m.FQMN(), "."))
+		}
+		name, err := f.OutputPath(".txt")
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, &plugin.CodeGeneratorResponse_File{
+			Name:    proto.String(name),
+			Content: proto.String(b.String()),
+		})
+	}
+	return files, nil
+}
+
+// _FileDescriptorSet 返回包含foo/a.proto与bar/b.proto的描述符集合
+func _FileDescriptorSet() *descriptor.FileDescriptorSet {
+	return &descriptor.FileDescriptorSet{
+		File: []*descriptor.FileDescriptorProto{
+			{
+				Name:        proto.String("foo/a.proto"),
+				Package:     proto.String("foo"),
+				Syntax:      proto.String("proto3"),
+				Options:     &descriptor.FileOptions{GoPackage: proto.String("example.com/foo")},
+				MessageType: []*descriptor.DescriptorProto{{Name: proto.String("Req")}},
+			},
+			{
+				Name:        proto.String("bar/b.proto"),
+				Package:     proto.String("bar"),
+				Syntax:      proto.String("proto3"),
+				Options:     &descriptor.FileOptions{GoPackage: proto.String("example.com/bar")},
+				MessageType: []*descriptor.DescriptorProto{{Name: proto.String("Rsp")}},
+			},
+		},
+	}
+}
+
+// _TempDir 创建一个测试结束时删除的临时目录
+func _TempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "gengotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+// _WriteFile 写入文件,并创建需要的目录
+func _WriteFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}